@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	configpkg "github.com/thilobro/gofileyourself/internal/config"
+	"github.com/thilobro/gofileyourself/internal/paths"
+
+	"gopkg.in/yaml.v3"
+)
+
+// runConfigCommand runs gofileyourself config check|dump and returns the exit
+// code
+func runConfigCommand(args []string, configPath string) int {
+	if len(args) != 1 || (args[0] != "check" && args[0] != "dump") {
+		fmt.Fprintln(os.Stderr, "usage: gofileyourself [--config=<path>] config check|dump")
+		return 2
+	}
+	config, err := configpkg.Load(configPath)
+	var validationErrors configpkg.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		printValidationErrors(configPath, validationErrors)
+		return 1
+	} else if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", configPath, err)
+		return 1
+	}
+
+	if args[0] == "check" {
+		fmt.Printf("%s: ok\n", configPath)
+		return 0
+	}
+	encoder := yaml.NewEncoder(os.Stdout)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(config); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// printValidationErrors prints one error per line in the path:line:column
+// format that editors can jump to
+func printValidationErrors(configPath string, validationErrors configpkg.ValidationErrors) {
+	for _, err := range validationErrors {
+		fmt.Fprintf(os.Stderr, "%s:%d:%d: %s: %s\n", configPath, err.Line, err.Column, err.Field, err.Message)
+	}
+}
+
+// migrateLegacyFiles moves the state files that used to be kept in the home
+// directory to their configured locations
+func migrateLegacyFiles(config *configpkg.Config) {
+	legacyFiles := map[string]string{
+		".gofileyourselfhistory":        config.Paths.History,
+		".gofileyourself_prompthistory": config.Paths.PromptHistory,
+		".gofileyourself_anchors":       config.Paths.Anchors,
+	}
+	for legacyName, target := range legacyFiles {
+		if err := paths.Migrate(legacyName, target); err != nil {
+			fmt.Fprintf(os.Stderr, "could not move ~/%s to %s: %v\n", legacyName, target, err)
+		}
+	}
+}
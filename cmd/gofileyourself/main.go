@@ -1,25 +1,51 @@
 package main
 
 import (
+	"errors"
 	"flag"
+	"fmt"
 	"io"
 	"log"
 	"os"
+	"path/filepath"
 
+	configpkg "github.com/thilobro/gofileyourself/internal/config"
 	"github.com/thilobro/gofileyourself/internal/display"
 	"github.com/thilobro/gofileyourself/internal/explorer"
 	"github.com/thilobro/gofileyourself/internal/finder"
+	"github.com/thilobro/gofileyourself/internal/paths"
+	"github.com/thilobro/gofileyourself/internal/theme"
+	"github.com/thilobro/gofileyourself/internal/trash"
 	"github.com/thilobro/gofileyourself/internal/widget"
 )
 
 func main() {
-	defaultConfig := os.Getenv("HOME") + "/.gofileyourself.yaml"
-	config := flag.String("config", defaultConfig, "Path to config file")
+	configPath := flag.String("config", paths.ConfigFile(), "Path to config file")
 	debug := flag.Bool("debug", false, "Enable debug logging")
 	cfp := flag.String("choosefiles", "", "Use as a file chooser")
 	sf := flag.String("selectfile", "", "The file that was selected")
+	colorMode := flag.String("color", theme.COLOR_AUTO, "Colors: auto, always, never, 16 or 256")
 
 	flag.Parse()
+	if flag.Arg(0) == "config" {
+		os.Exit(runConfigCommand(flag.Args()[1:], *configPath))
+	}
+	if _, err := theme.ParseColorMode(*colorMode, 0); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	config, err := configpkg.NewConfig(configPath)
+	var validationErrors configpkg.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		printValidationErrors(*configPath, validationErrors)
+		os.Exit(1)
+	} else if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", *configPath, err)
+		os.Exit(1)
+	}
+	migrateLegacyFiles(config)
+
 	var chooseFilePath *string
 	if *cfp != "" {
 		chooseFilePath = cfp
@@ -34,7 +60,10 @@ func main() {
 	}
 
 	if *debug {
-		logFile, err := os.OpenFile("debug.log", os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o666)
+		if err := os.MkdirAll(filepath.Dir(config.Paths.DebugLog), 0o700); err != nil {
+			log.Fatalf("Error creating log directory: %v", err)
+		}
+		logFile, err := os.OpenFile(config.Paths.DebugLog, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o666)
 		if err != nil {
 			log.Fatalf("Error opening log file: %v", err)
 		}
@@ -52,11 +81,13 @@ func main() {
 		widget.Explorer:   &explorer.Factory{},
 		widget.Find:       &finder.Factory{},
 		widget.FindRecent: &finder.Factory{},
+		widget.Trash:      &trash.Factory{},
 	}
 
-	display, err := display.NewDisplay(factories, chooseFilePath, selectedFilePath, config)
+	display, err := display.NewDisplay(factories, chooseFilePath, selectedFilePath, *configPath, config, *colorMode)
 	if err != nil {
-		panic(err)
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
 
 	if err := display.Run(); err != nil {
@@ -4,16 +4,17 @@ go 1.22.1
 
 require (
 	github.com/alecthomas/chroma v0.10.0
-	github.com/gdamore/tcell v1.4.0
+	github.com/boyter/go-string v1.0.5
+	github.com/creasty/defaults v1.8.0
 	github.com/gdamore/tcell/v2 v2.7.1
 	github.com/otiai10/copy v1.14.1
 	github.com/rivo/tview v0.0.0-20250330220935-949945f8d922
 	github.com/sahilm/fuzzy v0.1.1
+	golang.org/x/term v0.17.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
-	github.com/boyter/go-string v1.0.5 // indirect
-	github.com/creasty/defaults v1.8.0 // indirect
 	github.com/dlclark/regexp2 v1.4.0 // indirect
 	github.com/gdamore/encoding v1.0.0 // indirect
 	github.com/kylelemons/godebug v1.1.0 // indirect
@@ -23,7 +24,5 @@ require (
 	github.com/rivo/uniseg v0.4.7 // indirect
 	golang.org/x/sync v0.8.0 // indirect
 	golang.org/x/sys v0.24.0 // indirect
-	golang.org/x/term v0.17.0 // indirect
 	golang.org/x/text v0.14.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
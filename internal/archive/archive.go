@@ -0,0 +1,179 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"time"
+)
+
+// Formats of archives
+const (
+	FORMAT_ZIP     = "zip"
+	FORMAT_TAR     = "tar"
+	FORMAT_TAR_GZ  = "tar.gz"
+	FORMAT_TAR_BZ2 = "tar.bz2"
+)
+
+// TAR_MAGIC_OFFSET is the position of the magic "ustar" in a tar header
+const TAR_MAGIC_OFFSET = 257
+
+// Entry is a file in an archive
+type Entry struct {
+	Name string
+	// Link is the target of a symbolic or hard link
+	Link    string
+	Size    int64
+	Mode    fs.FileMode
+	ModTime time.Time
+}
+
+// Listing holds the entries of an archive
+type Listing struct {
+	Format  string
+	Entries []Entry
+	// Count and TotalSize include the entries that are not listed. They are
+	// complete unless IsCut is set.
+	Count     int
+	TotalSize int64
+	// IsCut is set when reading stopped before the end of a tar archive,
+	// after maxEntries entries or at a corrupt part
+	IsCut bool
+}
+
+// List reads the entries of a zip or tar archive, also compressed with gzip
+// or bzip2. Other files have no listing. At most maxEntries entries are
+// listed, all of them if it is 0; tar archives are not read any further.
+func List(listContext context.Context, path string, maxEntries int) (*Listing, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	header := make([]byte, TAR_MAGIC_OFFSET+5)
+	n, err := io.ReadFull(file, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	header = header[:n]
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	// Decompressing fails once the listing is no longer needed
+	reader := &contextReader{context: listContext, reader: file}
+	switch {
+	case bytes.HasPrefix(header, []byte("PK\x03\x04")) || bytes.HasPrefix(header, []byte("PK\x05\x06")):
+		info, err := file.Stat()
+		if err != nil {
+			return nil, err
+		}
+		return listZip(file, info.Size(), maxEntries)
+	case bytes.HasPrefix(header, []byte{0x1f, 0x8b}):
+		decompressed, err := gzip.NewReader(reader)
+		if err != nil {
+			return nil, err
+		}
+		return listCompressedTar(decompressed, FORMAT_TAR_GZ, maxEntries)
+	case bytes.HasPrefix(header, []byte("BZh")):
+		return listCompressedTar(bzip2.NewReader(reader), FORMAT_TAR_BZ2, maxEntries)
+	case isTar(header):
+		// The file is read directly, so that the tar reader seeks over the
+		// contents of the entries
+		return listTar(file, FORMAT_TAR, maxEntries)
+	}
+	return nil, nil
+}
+
+// isTar reports whether a block starts with a POSIX or GNU tar header
+func isTar(block []byte) bool {
+	return len(block) >= TAR_MAGIC_OFFSET+5 && string(block[TAR_MAGIC_OFFSET:TAR_MAGIC_OFFSET+5]) == "ustar"
+}
+
+// listZip reads the central directory of a zip archive, which holds all
+// entries, so that only the listing is cut. Broken archives have no listing.
+func listZip(file io.ReaderAt, size int64, maxEntries int) (*Listing, error) {
+	archive, err := zip.NewReader(file, size)
+	if errors.Is(err, zip.ErrFormat) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	listing := &Listing{Format: FORMAT_ZIP, Entries: []Entry{}, Count: len(archive.File)}
+	for _, file := range archive.File {
+		listing.TotalSize += int64(file.UncompressedSize64)
+		if maxEntries > 0 && len(listing.Entries) >= maxEntries {
+			continue
+		}
+		listing.Entries = append(listing.Entries, Entry{
+			Name:    file.Name,
+			Size:    int64(file.UncompressedSize64),
+			Mode:    file.Mode(),
+			ModTime: file.Modified,
+		})
+	}
+	return listing, nil
+}
+
+// listCompressedTar lists the tar archive in a decompressed stream. Other
+// compressed files have no listing.
+func listCompressedTar(reader io.Reader, format string, maxEntries int) (*Listing, error) {
+	buffered := bufio.NewReader(reader)
+	if block, _ := buffered.Peek(TAR_MAGIC_OFFSET + 5); !isTar(block) {
+		return nil, nil
+	}
+	return listTar(buffered, format, maxEntries)
+}
+
+// listTar reads the headers of a tar archive
+func listTar(reader io.Reader, format string, maxEntries int) (*Listing, error) {
+	archive := tar.NewReader(reader)
+	listing := &Listing{Format: format, Entries: []Entry{}}
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return listing, nil
+		} else if err != nil {
+			// Show the entries read before a truncated or corrupt part
+			if len(listing.Entries) > 0 && !errors.Is(err, context.Canceled) {
+				listing.IsCut = true
+				return listing, nil
+			}
+			return nil, err
+		}
+		if maxEntries > 0 && len(listing.Entries) >= maxEntries {
+			listing.IsCut = true
+			return listing, nil
+		}
+		listing.Entries = append(listing.Entries, Entry{
+			Name:    header.Name,
+			Link:    header.Linkname,
+			Size:    header.Size,
+			Mode:    header.FileInfo().Mode(),
+			ModTime: header.ModTime,
+		})
+		listing.Count++
+		listing.TotalSize += header.Size
+	}
+}
+
+// contextReader stops reading once its context is cancelled, e.g. while
+// decompressing a large entry of an archive
+type contextReader struct {
+	context context.Context
+	reader  io.Reader
+}
+
+func (reader *contextReader) Read(buffer []byte) (int, error) {
+	if err := reader.context.Err(); err != nil {
+		return 0, err
+	}
+	return reader.reader.Read(buffer)
+}
@@ -0,0 +1,348 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ArgType describes which values an argument accepts
+type ArgType int
+
+const (
+	String ArgType = iota
+	Int
+	Path
+	CommandName
+	// Rest takes the unparsed remainder of the command line
+	Rest
+)
+
+// Arg is the specification of a single command argument
+type Arg struct {
+	Name     string
+	Type     ArgType
+	Optional bool
+	Variadic bool
+	// Choices are offered for completion
+	Choices []string
+}
+
+// Command is a footer command that can be run as :name args
+type Command struct {
+	Name        string
+	Aliases     []string
+	Args        []Arg
+	Description string
+	Run         func(args Args) error
+}
+
+// Usage returns the synopsis of a command, e.g. mkdir <directory>...
+func (command *Command) Usage() string {
+	usage := command.Name
+	for _, arg := range command.Args {
+		name := arg.Name
+		if arg.Variadic {
+			name += "..."
+		}
+		if arg.Optional {
+			usage += " [" + name + "]"
+		} else {
+			usage += " <" + name + ">"
+		}
+	}
+	return usage
+}
+
+// Args holds the parsed arguments of a command by name
+type Args struct {
+	values map[string][]string
+}
+
+// String returns the value of an argument or an empty string
+func (args Args) String(name string) string {
+	if values := args.values[name]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// Strings returns all values of a variadic argument
+func (args Args) Strings(name string) []string {
+	return args.values[name]
+}
+
+// Int returns the value of an integer argument
+func (args Args) Int(name string) int {
+	value, _ := strconv.Atoi(args.String(name))
+	return value
+}
+
+// Has reports whether an optional argument was given
+func (args Args) Has(name string) bool {
+	return len(args.values[name]) > 0
+}
+
+// UsageError is returned when the arguments do not match the specification
+type UsageError struct {
+	Command *Command
+	Reason  string
+}
+
+func (err *UsageError) Error() string {
+	return err.Reason + ", usage: " + err.Command.Usage()
+}
+
+// Registry holds all commands available in the footer command line
+type Registry struct {
+	commands map[string]*Command
+	aliases  map[string]string
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		commands: make(map[string]*Command),
+		aliases:  make(map[string]string),
+	}
+}
+
+// Register adds a command, replacing an existing one with the same name
+func (registry *Registry) Register(command *Command) {
+	registry.commands[command.Name] = command
+	for _, alias := range command.Aliases {
+		registry.aliases[alias] = command.Name
+	}
+}
+
+// Lookup finds a command by name or alias
+func (registry *Registry) Lookup(name string) (*Command, bool) {
+	if commandName, isAlias := registry.aliases[name]; isAlias {
+		name = commandName
+	}
+	command, exists := registry.commands[name]
+	return command, exists
+}
+
+// Names returns the sorted names of all commands
+func (registry *Registry) Names() []string {
+	names := make([]string, 0, len(registry.commands))
+	for name := range registry.commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SplitName splits a command line into the command name and the unparsed
+// remainder. A leading ! is a command name of its own, as in :!ls.
+func SplitName(line string) (string, string) {
+	line = strings.TrimLeft(line, " ")
+	if strings.HasPrefix(line, "!") {
+		return "!", line[1:]
+	}
+	name, rest, _ := strings.Cut(line, " ")
+	return name, rest
+}
+
+// Execute parses a command line and runs the matching command
+func (registry *Registry) Execute(line string) error {
+	name, rest := SplitName(line)
+	if name == "" {
+		return nil
+	}
+	command, exists := registry.Lookup(name)
+	if !exists {
+		return fmt.Errorf("unknown command: %s", name)
+	}
+	args, err := Parse(command, rest)
+	if err == nil {
+		err = command.Run(args)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", command.Name, err)
+	}
+	return nil
+}
+
+// Parse checks the arguments of a command line against the specification
+func Parse(command *Command, rest string) (Args, error) {
+	args := Args{values: make(map[string][]string)}
+	if len(command.Args) == 1 && command.Args[0].Type == Rest {
+		arg := command.Args[0]
+		rest = strings.TrimSpace(rest)
+		if rest == "" && !arg.Optional {
+			return args, &UsageError{Command: command, Reason: "missing " + arg.Name}
+		}
+		if rest != "" {
+			args.values[arg.Name] = []string{rest}
+		}
+		return args, nil
+	}
+
+	tokens, err := Tokenize(rest)
+	if err != nil {
+		return args, &UsageError{Command: command, Reason: err.Error()}
+	}
+	for _, arg := range command.Args {
+		if len(tokens) == 0 {
+			if !arg.Optional {
+				return args, &UsageError{Command: command, Reason: "missing " + arg.Name}
+			}
+			continue
+		}
+		count := 1
+		if arg.Variadic {
+			count = len(tokens)
+		}
+		for _, token := range tokens[:count] {
+			if arg.Type == Int {
+				if _, err := strconv.Atoi(token); err != nil {
+					return args, &UsageError{Command: command, Reason: arg.Name + " must be a number"}
+				}
+			}
+		}
+		args.values[arg.Name] = tokens[:count]
+		tokens = tokens[count:]
+	}
+	if len(tokens) > 0 {
+		return args, &UsageError{Command: command, Reason: "too many arguments"}
+	}
+	return args, nil
+}
+
+// Tokenize splits a line into words like a shell does: words are separated
+// by spaces, single quotes keep everything literal, double quotes allow
+// backslash escapes and a backslash outside of quotes escapes any character
+func Tokenize(line string) ([]string, error) {
+	tokens := []string{}
+	var token strings.Builder
+	isInWord := false
+	var quote rune
+	isEscaped := false
+	for _, r := range line {
+		switch {
+		case isEscaped:
+			token.WriteRune(r)
+			isEscaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				token.WriteRune(r)
+			}
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else if r == '\\' {
+				isEscaped = true
+			} else {
+				token.WriteRune(r)
+			}
+		case r == '\\':
+			isEscaped = true
+			isInWord = true
+		case r == '\'' || r == '"':
+			quote = r
+			isInWord = true
+		case r == ' ' || r == '\t':
+			if isInWord {
+				tokens = append(tokens, token.String())
+				token.Reset()
+				isInWord = false
+			}
+		default:
+			token.WriteRune(r)
+			isInWord = true
+		}
+	}
+	if isInWord {
+		tokens = append(tokens, token.String())
+	}
+	if quote != 0 {
+		return tokens, errors.New("unterminated quote")
+	}
+	if isEscaped {
+		return tokens, errors.New("trailing backslash")
+	}
+	return tokens, nil
+}
+
+// LastWord splits an incomplete line into the text in front of its last
+// word and the unquoted value of that word. A line ending in a space has an
+// empty last word.
+func LastWord(line string) (string, string) {
+	wordStart := len(line)
+	isInWord := false
+	var quote rune
+	isEscaped := false
+	for i, r := range line {
+		switch {
+		case isEscaped:
+			isEscaped = false
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else if r == '\\' && quote == '"' {
+				isEscaped = true
+			}
+		case r == ' ' || r == '\t':
+			isInWord = false
+			continue
+		case r == '\\':
+			isEscaped = true
+		case r == '\'' || r == '"':
+			quote = r
+		}
+		if !isInWord {
+			wordStart = i
+			isInWord = true
+		}
+	}
+	if !isInWord {
+		return line, ""
+	}
+	// The word may lack a closing quote or end in a backslash while typing
+	tokens, _ := Tokenize(line[wordStart:])
+	word := ""
+	if len(tokens) > 0 {
+		word = tokens[0]
+	}
+	return line[:wordStart], word
+}
+
+// Escape escapes a value with backslashes so that it is a single word
+func Escape(value string) string {
+	var builder strings.Builder
+	for _, r := range value {
+		if strings.ContainsRune(" \t\\'\"", r) {
+			builder.WriteRune('\\')
+		}
+		builder.WriteRune(r)
+	}
+	return builder.String()
+}
+
+// Help returns the usage and description of one command, or of all commands
+// when name is empty
+func (registry *Registry) Help(name string) ([]string, error) {
+	names := registry.Names()
+	if name != "" {
+		command, exists := registry.Lookup(name)
+		if !exists {
+			return nil, fmt.Errorf("unknown command: %s", name)
+		}
+		names = []string{command.Name}
+	}
+	lines := []string{}
+	for _, commandName := range names {
+		command := registry.commands[commandName]
+		line := ":" + command.Usage()
+		if len(command.Aliases) > 0 {
+			line += "  (" + strings.Join(command.Aliases, ", ") + ")"
+		}
+		lines = append(lines, line, "    "+command.Description)
+	}
+	return lines, nil
+}
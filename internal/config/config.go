@@ -1,32 +1,210 @@
 package config
 
 import (
-	"log"
+	"errors"
+	"io/fs"
 	"os"
+	"path/filepath"
+
+	"github.com/thilobro/gofileyourself/internal/paths"
 
 	"github.com/creasty/defaults"
 
 	"gopkg.in/yaml.v3"
 )
 
+// Confirmation modes for destructive actions
+const (
+	ConfirmAlways    = "always"
+	ConfirmNever     = "never"
+	ConfirmRecursive = "recursive"
+)
+
+// Protocols to draw pictures in the preview with
+const (
+	ImageNone       = "none"
+	ImageHalfBlocks = "halfblocks"
+	ImageKitty      = "kitty"
+)
+
+// Sort orders of directory listings
+const (
+	SortName    = "name"
+	SortNatural = "natural"
+	SortSize    = "size"
+	SortMtime   = "mtime"
+	SortExt     = "ext"
+)
+
 type Config struct {
-	HistoryLen int `default:"50" yaml:"history_len"`
+	HistoryLen       int           `default:"50" yaml:"history_len"`
+	PromptHistoryLen int           `default:"200" yaml:"prompt_history_len"`
+	Theme            string        `default:"gruvbox" yaml:"theme"`
+	ShowHidden       bool          `default:"false" yaml:"show_hidden"`
+	Sort             string        `default:"name" yaml:"sort"`
+	SortReverse      bool          `default:"false" yaml:"sort_reverse"`
+	DirsFirst        bool          `default:"true" yaml:"dirs_first"`
+	Preview          PreviewConfig `yaml:"preview"`
+	Icons            IconsConfig   `yaml:"icons"`
+	Trash            bool          `default:"true" yaml:"trash"`
+	Confirm          ConfirmConfig `yaml:"confirm"`
+	Editor           EditorConfig  `yaml:"editor"`
+	Openers          []OpenerRule  `yaml:"openers"`
+	Commands         []UserCommand `yaml:"commands"`
+	// Keymap binds key sequences like gd or special keys like Ctrl+G to
+	// command lines, an empty one unbinds a default key
+	Keymap      map[string]string `yaml:"keymap"`
+	Paths       PathsConfig       `yaml:"paths"`
+	LocalConfig LocalConfig       `yaml:"local_config"`
 }
 
-func NewConfig(configPath *string) (*Config, error) {
-	configFile, err := os.ReadFile(*configPath)
-	if err != nil {
-		return &Config{HistoryLen: 50}, nil
+// PathsConfig overrides where state is kept. Empty values are filled with
+// locations in the XDG base directories by Load.
+type PathsConfig struct {
+	History       string `yaml:"history"`
+	PromptHistory string `yaml:"prompt_history"`
+	Anchors       string `yaml:"anchors"`
+	LastDir       string `yaml:"lastdir"`
+	DebugLog      string `yaml:"debug_log"`
+	CacheDir      string `yaml:"cache_dir"`
+	Trust         string `yaml:"trust"`
+}
+
+func (pathsConfig *PathsConfig) resolve() {
+	for _, path := range []struct {
+		value        *string
+		defaultValue string
+	}{
+		{&pathsConfig.History, paths.StateFile("history")},
+		{&pathsConfig.PromptHistory, paths.StateFile("prompt_history")},
+		{&pathsConfig.Anchors, paths.StateFile("anchors")},
+		// Shell wrappers of S read the last directory from the home directory
+		{&pathsConfig.LastDir, filepath.Join(paths.HomeDir(), ".gofileyourself_lastdir")},
+		{&pathsConfig.DebugLog, paths.StateFile("debug.log")},
+		{&pathsConfig.CacheDir, paths.CacheDir()},
+		{&pathsConfig.Trust, paths.StateFile("trusted")},
+	} {
+		if *path.value == "" {
+			*path.value = path.defaultValue
+		} else {
+			*path.value = paths.Expand(*path.value)
+		}
 	}
+}
+
+// UserCommand is a footer command that runs one or more command lines, e.g.
+// !rsync -av %s host:/srv
+type UserCommand struct {
+	Name        string     `yaml:"name"`
+	Aliases     []string   `yaml:"aliases"`
+	Description string     `yaml:"description"`
+	Run         StringList `yaml:"run"`
+}
+
+// StringList is a list of strings that can also be written as a single string
+type StringList []string
+
+func (list *StringList) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*list = StringList{node.Value}
+		return nil
+	}
+	var values []string
+	if err := node.Decode(&values); err != nil {
+		return err
+	}
+	*list = values
+	return nil
+}
+
+// EditorConfig overrides the editor taken from $VISUAL or $EDITOR. The
+// template places {file}, {line} and {col} in the editor arguments.
+type EditorConfig struct {
+	Command  string `yaml:"command"`
+	Template string `yaml:"template"`
+}
+
+// OpenerRule opens files that match all of its conditions with a command.
+// Without a command the file is opened in the editor.
+type OpenerRule struct {
+	Name     string   `yaml:"name"`
+	Ext      []string `yaml:"ext"`
+	Mime     []string `yaml:"mime"`
+	Glob     []string `yaml:"glob"`
+	Terminal *bool    `yaml:"terminal"`
+	Text     *bool    `yaml:"text"`
+	Command  string   `yaml:"command"`
+	Mode     string   `yaml:"mode"`
+}
+
+// PreviewConfig controls the preview of the selected file or directory. Files
+// are read in chunks of at most MaxBytes and MaxLines, 0 for no limit.
+type PreviewConfig struct {
+	Enabled     bool `default:"true" yaml:"enabled"`
+	Highlight   bool `default:"true" yaml:"highlight"`
+	LineNumbers bool `default:"false" yaml:"line_numbers"`
+	Wrap        bool `default:"true" yaml:"wrap"`
+	MaxBytes    int  `default:"1048576" yaml:"max_bytes"`
+	MaxLines    int  `default:"5000" yaml:"max_lines"`
+	// HighlightMaxBytes is the size above which files are not highlighted
+	HighlightMaxBytes int `default:"262144" yaml:"highlight_max_bytes"`
+	// HexMaxBytes limits the hex dump of binary files
+	HexMaxBytes int `default:"65536" yaml:"hex_max_bytes"`
+	// ArchiveMaxEntries limits the entries listed for zip and tar archives
+	ArchiveMaxEntries int `default:"1000" yaml:"archive_max_entries"`
+	// ImageProtocol draws pictures with colored half blocks, with the kitty
+	// graphics protocol or not at all
+	ImageProtocol string `default:"halfblocks" yaml:"image_protocol"`
+	// Tail lists patterns of files that are previewed from their end
+	Tail []string `default:"[\"*.log\"]" yaml:"tail"`
+}
+
+// IconsConfig shows Nerd Font icons in front of file names. The icons replace
+// or add to the built-in ones; extensions are written without the dot.
+type IconsConfig struct {
+	Enabled     bool              `default:"false" yaml:"enabled"`
+	Extensions  map[string]string `yaml:"extensions"`
+	Files       map[string]string `yaml:"files"`
+	Directories map[string]string `yaml:"directories"`
+}
+
+// ConfirmConfig sets per action whether a confirmation dialog is shown
+type ConfirmConfig struct {
+	Delete string `default:"always" yaml:"delete"`
+	Trash  string `default:"never" yaml:"trash"`
+}
+
+func NewConfig(configPath *string) (*Config, error) {
+	return Load(*configPath)
+}
+
+// Load reads the config file on top of the defaults. A missing file results
+// in the defaults, an invalid one in ValidationErrors.
+func Load(path string) (*Config, error) {
 	var config Config
 	if err := defaults.Set(&config); err != nil {
-		log.Fatal(err)
-		panic(err)
+		return nil, err
+	}
+	// Fill in the paths once the file was decoded, or when there is none
+	defer config.Paths.resolve()
+	configFile, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &config, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var document yaml.Node
+	if err := yaml.Unmarshal(configFile, &document); err != nil {
+		return nil, err
+	}
+	if len(document.Content) == 0 {
+		return &config, nil
+	}
+	if errs := Validate(&document); len(errs) > 0 {
+		return nil, errs
 	}
-	if err := yaml.Unmarshal(configFile, &config); err != nil {
-		log.Fatal(err)
-		panic(err)
+	if err := document.Decode(&config); err != nil {
+		return nil, err
 	}
-	log.Print(config)
 	return &config, nil
 }
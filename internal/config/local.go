@@ -0,0 +1,257 @@
+package config
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/thilobro/gofileyourself/internal/paths"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LOCAL_CONFIG_FILE overrides the config for the directory it is in and all
+// directories below
+const LOCAL_CONFIG_FILE = ".gofileyourself.yaml"
+
+// globalOnlyOptions cannot be set by local config files
+var globalOnlyOptions = []string{"paths", "local_config", "prompt_history_len"}
+
+// LocalConfig controls the lookup of local config files
+type LocalConfig struct {
+	Enabled bool `default:"true" yaml:"enabled"`
+	// Local config files below these directories are trusted without asking
+	Trusted []string `yaml:"trusted"`
+}
+
+// localLookup is the local config file of a directory, "" if it has none,
+// found while the directory had the modification time dirModTime
+type localLookup struct {
+	dirModTime time.Time
+	file       string
+}
+
+// localLookups caches the lookups of FindLocal by directory
+var localLookups = map[string]localLookup{}
+
+// trustCheck is the result of IsTrusted for a local config file with the
+// modification time and size of the file and of the allowlist
+type trustCheck struct {
+	modTime      time.Time
+	size         int64
+	trustModTime time.Time
+	isTrusted    bool
+}
+
+// trustChecks caches the results of IsTrusted by file, so that files are only
+// hashed again once they or the allowlist change
+var trustChecks = map[string]trustCheck{}
+
+// FindLocal returns the local config files in dir and its parents, outermost
+// first. The legacy global config in the home directory is not a local one.
+// Only dir is looked up again when its modification time changed, the
+// lookups of the parents are kept until ClearLocalLookups.
+func FindLocal(dir string) []string {
+	files := []string{}
+	for isParent := false; ; isParent = true {
+		if file := findLocalIn(dir, isParent); file != "" {
+			files = append(files, file)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	slices.Reverse(files)
+	return files
+}
+
+// findLocalIn returns the local config file of dir, from the cache for
+// parents or while the modification time of dir is unchanged
+func findLocalIn(dir string, isParent bool) string {
+	lookup, isCached := localLookups[dir]
+	if isCached && isParent {
+		return lookup.file
+	}
+	dirInfo, err := os.Stat(dir)
+	if err != nil {
+		return ""
+	}
+	if isCached && lookup.dirModTime.Equal(dirInfo.ModTime()) {
+		return lookup.file
+	}
+	lookup = localLookup{dirModTime: dirInfo.ModTime()}
+	file := filepath.Join(dir, LOCAL_CONFIG_FILE)
+	legacyConfigFile := filepath.Join(paths.HomeDir(), paths.LEGACY_CONFIG_FILE)
+	if info, err := os.Stat(file); err == nil && info.Mode().IsRegular() && file != legacyConfigFile {
+		lookup.file = file
+	}
+	localLookups[dir] = lookup
+	return lookup.file
+}
+
+// ClearLocalLookups makes FindLocal look up all directories again
+func ClearLocalLookups() {
+	clear(localLookups)
+}
+
+// Merge returns a copy of the config with the local config files applied in
+// order. Openers of local files are tried before the inherited ones and their
+// commands replace inherited commands of the same name.
+func (config *Config) Merge(files []string) (*Config, error) {
+	merged := *config
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return config, err
+		}
+		var document yaml.Node
+		if err := yaml.Unmarshal(content, &document); err != nil {
+			return config, fmt.Errorf("%s: %w", file, err)
+		}
+		if len(document.Content) == 0 {
+			continue
+		}
+		errs := Validate(&document)
+		for _, option := range globalOnlyOptions {
+			if keyNode := mappingKey(document.Content[0], option); keyNode != nil {
+				errs = append(errs, &ValidationError{
+					Line:    keyNode.Line,
+					Column:  keyNode.Column,
+					Field:   option,
+					Message: "can only be set in the global config",
+				})
+			}
+		}
+		if len(errs) > 0 {
+			return config, fmt.Errorf("%s: %w", file, errs)
+		}
+
+		inheritedOpeners := merged.Openers
+		inheritedCommands := merged.Commands
+		merged.Openers = nil
+		merged.Commands = nil
+		// Decoding adds to existing maps, which must not be the inherited ones
+		merged.Keymap = maps.Clone(merged.Keymap)
+		merged.Icons.Extensions = maps.Clone(merged.Icons.Extensions)
+		merged.Icons.Files = maps.Clone(merged.Icons.Files)
+		merged.Icons.Directories = maps.Clone(merged.Icons.Directories)
+		if err := document.Decode(&merged); err != nil {
+			return config, fmt.Errorf("%s: %w", file, err)
+		}
+		merged.Openers = append(merged.Openers, inheritedOpeners...)
+		for _, inheritedCommand := range inheritedCommands {
+			isOverridden := slices.ContainsFunc(merged.Commands, func(command UserCommand) bool {
+				return command.Name == inheritedCommand.Name
+			})
+			if !isOverridden {
+				merged.Commands = append(merged.Commands, inheritedCommand)
+			}
+		}
+	}
+	return &merged, nil
+}
+
+func mappingKey(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i]
+		}
+	}
+	return nil
+}
+
+// IsTrusted reports whether a local config file may be applied: it is below
+// one of the trusted directories, or its current content was trusted with
+// Trust
+func (config *Config) IsTrusted(file string) bool {
+	for _, dir := range config.LocalConfig.Trusted {
+		if relPath, err := filepath.Rel(paths.Expand(dir), file); err == nil && relPath != ".." && !strings.HasPrefix(relPath, "../") {
+			return true
+		}
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		return false
+	}
+	check := trustCheck{modTime: info.ModTime(), size: info.Size()}
+	if trustInfo, err := os.Stat(config.Paths.Trust); err == nil {
+		check.trustModTime = trustInfo.ModTime()
+	}
+	if cachedCheck, isCached := trustChecks[file]; isCached && cachedCheck.modTime.Equal(check.modTime) &&
+		cachedCheck.size == check.size && cachedCheck.trustModTime.Equal(check.trustModTime) {
+		return cachedCheck.isTrusted
+	}
+	if hash, err := hashFile(file); err == nil {
+		check.isTrusted = slices.Contains(readTrustFile(config.Paths.Trust), hash+" "+file)
+	}
+	trustChecks[file] = check
+	return check.isTrusted
+}
+
+// Trust adds the current content of a local config file to the allowlist.
+// Changing the file revokes the trust.
+func (config *Config) Trust(file string) error {
+	hash, err := hashFile(file)
+	if err != nil {
+		return err
+	}
+	return config.writeTrustFile(file, hash+" "+file)
+}
+
+// Untrust removes a local config file from the allowlist
+func (config *Config) Untrust(file string) error {
+	return config.writeTrustFile(file, "")
+}
+
+// writeTrustFile replaces the entries of file in the allowlist by entry
+func (config *Config) writeTrustFile(file string, entry string) error {
+	delete(trustChecks, file)
+	entries := slices.DeleteFunc(readTrustFile(config.Paths.Trust), func(existing string) bool {
+		_, existingFile, _ := strings.Cut(existing, " ")
+		return existingFile == file
+	})
+	if entry != "" {
+		entries = append(entries, entry)
+	}
+	if err := os.MkdirAll(filepath.Dir(config.Paths.Trust), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(config.Paths.Trust, []byte(strings.Join(entries, "\n")+"\n"), 0o600)
+}
+
+func readTrustFile(path string) []string {
+	entries := []string{}
+	file, err := os.Open(path)
+	if err != nil {
+		return entries
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			entries = append(entries, line)
+		}
+	}
+	return entries
+}
+
+func hashFile(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:]), nil
+}
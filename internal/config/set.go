@@ -0,0 +1,111 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// allowedValues restricts options that only accept a fixed set of values
+var allowedValues = map[string][]string{
+	"sort":                   {SortName, SortNatural, SortSize, SortMtime, SortExt},
+	"preview.image_protocol": {ImageNone, ImageHalfBlocks, ImageKitty},
+	"confirm.delete":         {ConfirmAlways, ConfirmNever, ConfirmRecursive},
+	"confirm.trash":          {ConfirmAlways, ConfirmNever, ConfirmRecursive},
+	"openers.mode":           {"foreground", "background", "detached"},
+}
+
+// Keys returns the dotted names of all options that can be changed with Set,
+// e.g. preview.highlight
+func Keys() []string {
+	keys := []string{}
+	var collect func(structType reflect.Type, prefix string)
+	collect = func(structType reflect.Type, prefix string) {
+		for i := 0; i < structType.NumField(); i++ {
+			field := structType.Field(i)
+			name := prefix + yamlName(field)
+			switch field.Type.Kind() {
+			case reflect.Struct:
+				collect(field.Type, name+".")
+			case reflect.Bool, reflect.Int, reflect.String:
+				keys = append(keys, name)
+			}
+		}
+	}
+	collect(reflect.TypeOf(Config{}), "")
+	return keys
+}
+
+// IsBool reports whether an option is a boolean
+func IsBool(key string) bool {
+	field, err := (&Config{}).field(key)
+	return err == nil && field.Kind() == reflect.Bool
+}
+
+// Get returns the value of an option as it would be written in YAML
+func (config *Config) Get(key string) (string, error) {
+	field, err := config.field(key)
+	if err != nil {
+		return "", err
+	}
+	value, err := yaml.Marshal(field.Interface())
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(value)), nil
+}
+
+// Set parses value as YAML and assigns it to an option
+func (config *Config) Set(key string, value string) error {
+	field, err := config.field(key)
+	if err != nil {
+		return err
+	}
+	parsed := reflect.New(field.Type())
+	if field.Kind() == reflect.String {
+		parsed.Elem().SetString(value)
+	} else if err := yaml.Unmarshal([]byte(value), parsed.Interface()); err != nil || value == "" {
+		return fmt.Errorf("invalid value for %s: %q", key, value)
+	}
+	if allowed, isRestricted := allowedValues[key]; isRestricted && !slices.Contains(allowed, parsed.Elem().String()) {
+		return fmt.Errorf("%s must be one of %s", key, strings.Join(allowed, ", "))
+	}
+	field.Set(parsed.Elem())
+	return nil
+}
+
+func (config *Config) field(key string) (reflect.Value, error) {
+	value := reflect.ValueOf(config).Elem()
+	for _, name := range strings.Split(key, ".") {
+		if value.Kind() != reflect.Struct {
+			return reflect.Value{}, fmt.Errorf("unknown option: %s", key)
+		}
+		found := false
+		for i := 0; i < value.NumField(); i++ {
+			if yamlName(value.Type().Field(i)) == name {
+				value = value.Field(i)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return reflect.Value{}, fmt.Errorf("unknown option: %s", key)
+		}
+	}
+	switch value.Kind() {
+	case reflect.Bool, reflect.Int, reflect.String:
+		return value, nil
+	}
+	return reflect.Value{}, fmt.Errorf("%s can only be changed in the config file", key)
+}
+
+func yamlName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+	if name == "" {
+		return strings.ToLower(field.Name)
+	}
+	return name
+}
@@ -0,0 +1,152 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ValidationError points at an invalid entry of the config file
+type ValidationError struct {
+	Line    int
+	Column  int
+	Field   string
+	Message string
+}
+
+func (err *ValidationError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s: %s", err.Line, err.Column, err.Field, err.Message)
+}
+
+// ValidationErrors are all problems found in a config file
+type ValidationErrors []*ValidationError
+
+func (errs ValidationErrors) Error() string {
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+var indexRegexp = regexp.MustCompile(`\[\d+\]`)
+
+var stringListType = reflect.TypeOf(StringList{})
+
+// Validate checks a parsed config file against the fields of Config. It
+// reports unknown options, values of the wrong type and values outside of
+// the allowed ones.
+func Validate(document *yaml.Node) ValidationErrors {
+	errs := ValidationErrors{}
+	if document.Kind == yaml.DocumentNode && len(document.Content) > 0 {
+		validateNode(document.Content[0], reflect.TypeOf(Config{}), "", &errs)
+	}
+	return errs
+}
+
+func validateNode(node *yaml.Node, valueType reflect.Type, field string, errs *ValidationErrors) {
+	report := func(format string, args ...any) {
+		*errs = append(*errs, &ValidationError{
+			Line:    node.Line,
+			Column:  node.Column,
+			Field:   field,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if node.Tag == "!!null" {
+		return
+	}
+	if valueType == stringListType {
+		if node.Kind == yaml.ScalarNode {
+			return
+		}
+		valueType = reflect.TypeOf([]string{})
+	}
+
+	switch valueType.Kind() {
+	case reflect.Pointer:
+		validateNode(node, valueType.Elem(), field, errs)
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			report("expected a mapping of options")
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			keyNode, valueNode := node.Content[i], node.Content[i+1]
+			name := keyNode.Value
+			if field != "" {
+				name = field + "." + name
+			}
+			structField, found := structFieldByYamlName(valueType, keyNode.Value)
+			if !found {
+				*errs = append(*errs, &ValidationError{
+					Line:    keyNode.Line,
+					Column:  keyNode.Column,
+					Field:   name,
+					Message: "unknown option",
+				})
+				continue
+			}
+			validateNode(valueNode, structField.Type, name, errs)
+		}
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			report("expected a list")
+			return
+		}
+		for i, item := range node.Content {
+			validateNode(item, valueType.Elem(), fmt.Sprintf("%s[%d]", field, i), errs)
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			report("expected a mapping")
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			validateNode(node.Content[i+1], valueType.Elem(), field+"."+node.Content[i].Value, errs)
+		}
+	case reflect.Bool:
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!bool" {
+			report("expected true or false, got %s", describe(node))
+		}
+	case reflect.Int:
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!int" {
+			report("expected a number, got %s", describe(node))
+		}
+	case reflect.String:
+		if node.Kind != yaml.ScalarNode {
+			report("expected a string, got %s", describe(node))
+			return
+		}
+		allowed, isRestricted := allowedValues[indexRegexp.ReplaceAllString(field, "")]
+		if isRestricted && !slices.Contains(allowed, node.Value) {
+			report("%q is not one of %s", node.Value, strings.Join(allowed, ", "))
+		}
+	}
+}
+
+func structFieldByYamlName(structType reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < structType.NumField(); i++ {
+		if yamlName(structType.Field(i)) == name {
+			return structType.Field(i), true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+func describe(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "a mapping"
+	case yaml.SequenceNode:
+		return "a list"
+	}
+	return fmt.Sprintf("%q", node.Value)
+}
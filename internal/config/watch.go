@@ -0,0 +1,32 @@
+package config
+
+import (
+	"os"
+	"time"
+)
+
+// WATCH_INTERVAL is the time between two checks of the config file
+const WATCH_INTERVAL = time.Second
+
+// Watch polls the modification time of the config file and calls onChange
+// with the reloaded config, or the error that prevented loading it, whenever
+// the file changes. It blocks and is meant to run in its own goroutine.
+func Watch(path string, onChange func(config *Config, err error)) {
+	lastModTime := modTime(path)
+	for range time.Tick(WATCH_INTERVAL) {
+		currentModTime := modTime(path)
+		if currentModTime.Equal(lastModTime) {
+			continue
+		}
+		lastModTime = currentModTime
+		onChange(Load(path))
+	}
+}
+
+func modTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
@@ -4,6 +4,8 @@ import (
 	"os"
 
 	"github.com/thilobro/gofileyourself/internal/config"
+	"github.com/thilobro/gofileyourself/internal/picture"
+	"github.com/thilobro/gofileyourself/internal/theme"
 	"github.com/thilobro/gofileyourself/internal/widget"
 
 	"github.com/gdamore/tcell/v2"
@@ -16,6 +18,7 @@ type Display struct {
 	mode          widget.Mode
 	activeWidget  widget.WidgetInterface
 	widgetFactory map[widget.Mode]widget.Factory
+	configPath    string
 }
 
 // setupKeyBindings configures keyboard input handling
@@ -33,9 +36,15 @@ func (display *Display) setupKeyBindings() {
 				inputHandler := display.activeWidget.GetInputCapture()
 				return inputHandler(event)
 			}
-		case tcell.KeyEscape:
-			display.setMode(widget.Explorer)
+		case tcell.KeyCtrlT:
+			display.setMode(widget.Trash)
 			return nil // Consume the event
+		case tcell.KeyEscape:
+			// The explorer handles escape itself, e.g. to cancel dialogs
+			if display.mode != widget.Explorer {
+				display.setMode(widget.Explorer)
+				return nil // Consume the event
+			}
 		}
 		// Let the active widget handle other keys
 		if display.activeWidget != nil {
@@ -69,33 +78,55 @@ func (display *Display) setActiveWidgetBasedOnMode(mode widget.Mode) {
 	display.context.App.SetRoot(display.activeWidget.Root(), true)
 }
 
-func NewDisplay(factories map[widget.Mode]widget.Factory, chooseFilePath *string, selectedFilePath *string, configPath *string) (*Display, error) {
-	app := tview.NewApplication()
-	config, _ := config.NewConfig(configPath)
+// NewDisplay sets up the terminal and the explorer. colorMode is a mode of
+// the --color flag, the colors of the terminal are detected for auto.
+func NewDisplay(factories map[widget.Mode]widget.Factory, chooseFilePath *string, selectedFilePath *string, configPath string, config *config.Config, colorMode string) (*Display, error) {
 	currentPath, err := os.Getwd()
 	if err != nil {
 		return nil, err
 	}
+
+	// The screen is set up before the widgets, which are styled for the color
+	// depth of the terminal
+	screen, err := tcell.NewScreen()
+	if err != nil {
+		return nil, err
+	}
+	if err := screen.Init(); err != nil {
+		return nil, err
+	}
+	colorDepth, err := theme.ParseColorMode(colorMode, screen.Colors())
+	if err != nil {
+		screen.Fini()
+		return nil, err
+	}
+	theme.SetColorDepth(colorDepth)
+	app := tview.NewApplication().SetScreen(screen)
+	// Pictures drawn with the kitty graphics protocol bypass the screen
+	app.SetAfterDrawFunc(picture.FlushKitty)
 	display := &Display{}
+	globalConfig := *config
 
 	explorerFactory := factories[widget.Explorer]
 	context := &widget.Context{
 		App:              app,
 		CurrentPath:      currentPath,
-		ShowHiddenFiles:  false,
 		OnWidgetResult:   display.onWidgetResult,
 		ChooseFilePath:   chooseFilePath,
 		SelectedFilePath: selectedFilePath,
 		Config:           config,
+		GlobalConfig:     &globalConfig,
 	}
 	explorerWidget, err := explorerFactory.New(context)
 	if err != nil {
+		screen.Fini()
 		return nil, err
 	}
 	display.context = context
 	display.activeWidget = explorerWidget
 	display.widgetFactory = factories
 	display.mode = widget.Explorer
+	display.configPath = configPath
 
 	return display, nil
 }
@@ -104,8 +135,23 @@ func (display *Display) onWidgetResult(mode widget.Mode, result string) {
 	display.setMode(widget.Explorer)
 }
 
+// onConfigChange replaces the shared config in place so that all widgets see
+// the new values and lets the active widget refresh itself
+func (display *Display) onConfigChange(newConfig *config.Config, err error) {
+	display.context.App.QueueUpdateDraw(func() {
+		if err == nil {
+			*display.context.Config = *newConfig
+			*display.context.GlobalConfig = *newConfig
+		}
+		if reloader, ok := display.activeWidget.(widget.ConfigReloader); ok {
+			reloader.ReloadConfig(err)
+		}
+	})
+}
+
 // Run starts the file explorer
 func (display *Display) Run() error {
 	display.setupKeyBindings()
+	go config.Watch(display.configPath, display.onConfigChange)
 	return display.context.App.SetRoot(display.activeWidget.Root(), true).Run()
 }
@@ -0,0 +1,132 @@
+package editor
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/thilobro/gofileyourself/internal/config"
+
+	"github.com/rivo/tview"
+)
+
+const (
+	DEFAULT_EDITOR   = "nvim"
+	DEFAULT_TEMPLATE = "+{line} {file}"
+)
+
+// lineTemplates maps known editors to the arguments that open a file at a
+// given line and column
+var lineTemplates = map[string]string{
+	"vi":    "+{line} {file}",
+	"vim":   "+call\\ cursor({line},{col}) {file}",
+	"nvim":  "+call\\ cursor({line},{col}) {file}",
+	"hx":    "{file}:{line}:{col}",
+	"helix": "{file}:{line}:{col}",
+	"kak":   "+{line}:{col} {file}",
+	"micro": "{file}:{line}:{col}",
+	"nano":  "+{line},{col} {file}",
+	"emacs": "+{line}:{col} {file}",
+	"code":  "--goto {file}:{line}:{col}",
+	"subl":  "{file}:{line}:{col}",
+}
+
+// Editor is a resolved editor command
+type Editor struct {
+	Command  []string
+	Template string
+}
+
+// Resolve picks the editor from the config, then $VISUAL, then $EDITOR
+func Resolve(editorConfig config.EditorConfig) *Editor {
+	command := editorConfig.Command
+	if command == "" {
+		command = os.Getenv("VISUAL")
+	}
+	if command == "" {
+		command = os.Getenv("EDITOR")
+	}
+	if command == "" {
+		command = DEFAULT_EDITOR
+	}
+	fields := strings.Fields(command)
+
+	template := editorConfig.Template
+	if template == "" {
+		template = lineTemplates[filepath.Base(fields[0])]
+	}
+	if template == "" {
+		template = DEFAULT_TEMPLATE
+	}
+	return &Editor{Command: fields, Template: template}
+}
+
+// Args returns the editor arguments that open path at line and column. A
+// line below one opens the file without a position.
+func (editor *Editor) Args(path string, line int, column int) []string {
+	if line < 1 {
+		return []string{path}
+	}
+	if column < 1 {
+		column = 1
+	}
+	replacer := strings.NewReplacer(
+		"{file}", path,
+		"{line}", strconv.Itoa(line),
+		"{col}", strconv.Itoa(column),
+	)
+	args := []string{}
+	for _, field := range splitTemplate(editor.Template) {
+		args = append(args, replacer.Replace(field))
+	}
+	return args
+}
+
+// Cmd returns the command that opens path at line and column
+func (editor *Editor) Cmd(path string, line int, column int) *exec.Cmd {
+	args := slices.Concat(editor.Command[1:], editor.Args(path, line, column))
+	cmd := exec.Command(editor.Command[0], args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd
+}
+
+// Edit suspends the application and waits until the editor exits
+func (editor *Editor) Edit(app *tview.Application, path string, line int, column int) error {
+	var err error
+	app.Suspend(func() {
+		err = editor.Cmd(path, line, column).Run()
+	})
+	return err
+}
+
+// splitTemplate splits a template on spaces that are not escaped by a backslash
+func splitTemplate(template string) []string {
+	fields := []string{}
+	field := ""
+	isEscaped := false
+	for _, r := range template {
+		switch {
+		case isEscaped:
+			field += string(r)
+			isEscaped = false
+		case r == '\\':
+			isEscaped = true
+		case r == ' ':
+			if field != "" {
+				fields = append(fields, field)
+			}
+			field = ""
+		default:
+			field += string(r)
+		}
+	}
+	if field != "" {
+		fields = append(fields, field)
+	}
+	return fields
+}
@@ -0,0 +1,287 @@
+package explorer
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/thilobro/gofileyourself/internal/command"
+	"github.com/thilobro/gofileyourself/internal/config"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// anchorRegexp matches the keys that set (A) or jump to (a) an anchor
+var anchorRegexp = regexp.MustCompile(`([Aa])([a-zA-Z0-9])$`)
+
+// sortOrders are cycled through by :sort
+var sortOrders = []string{config.SortName, config.SortNatural, config.SortSize, config.SortMtime, config.SortExt}
+
+// keyAction is a built-in action of the list. It is registered as a command,
+// so that user commands and the keymap can run it, and bound to its default
+// keys: sequences of characters like dd or names of special keys like Ctrl-D.
+type keyAction struct {
+	name        string
+	keys        []string
+	description string
+	run         func() error
+}
+
+// keyActions returns the built-in actions of the list
+func (fe *FileExplorer) keyActions() []keyAction {
+	moveBy := func(offset int) func() error {
+		return func() error {
+			fe.setCurrentLine(fe.currentList.GetCurrentItem() + offset)
+			return nil
+		}
+	}
+	halfPage := func() int {
+		return min(fe.currentList.GetItemCount()/2, MAX_SCROLL_AMOUNT)
+	}
+	scrollPreview := func(rows func() int) func() error {
+		return func() error {
+			if fe.preview != nil {
+				fe.preview.scroll(rows())
+			}
+			return nil
+		}
+	}
+	do := func(action func()) func() error {
+		return func() error {
+			action()
+			return nil
+		}
+	}
+	return []keyAction{
+		{"down", []string{"j"}, "Move the cursor down", moveBy(1)},
+		{"up", []string{"k"}, "Move the cursor up", moveBy(-1)},
+		{"half-page-down", []string{"Ctrl-D"}, "Move the cursor down half a list", func() error { return moveBy(halfPage())() }},
+		{"half-page-up", []string{"Ctrl-U"}, "Move the cursor up half a list", func() error { return moveBy(-halfPage())() }},
+		{"top", []string{"gg"}, "Move the cursor to the first entry", do(func() { fe.setCurrentLine(0) })},
+		{"bottom", []string{"G"}, "Move the cursor to the last entry", do(func() { fe.setCurrentLine(fe.currentList.GetItemCount() - 1) })},
+		{"open", []string{"l"}, "Enter the selected directory or open the selected file", fe.openCurrentEntry},
+		{"parent", []string{"h"}, "Go to the parent directory", func() error {
+			return fe.setCurrentDirectory(filepath.Join(fe.context.CurrentPath, ".."))
+		}},
+		{"open-with", []string{"o"}, "Choose an opener for the selected file", do(fe.openCurrentFileWith)},
+		{"toggle-hidden", []string{"Backspace"}, "Show or hide hidden files", func() error {
+			if err := fe.setConfigValue("show_hidden", strconv.FormatBool(!fe.context.Config.ShowHidden)); err != nil {
+				return err
+			}
+			return fe.refreshDirectory()
+		}},
+		{"search", []string{"/"}, "Search in the current directory", do(func() { fe.handleFooterInput("/") })},
+		{"command-line", []string{":"}, "Type a command", do(func() { fe.handleFooterInput(":") })},
+		{"next-match", []string{"n"}, "Move the cursor to the next search match", do(func() { fe.cycleSearch(false) })},
+		{"previous-match", []string{"N"}, "Move the cursor to the previous search match", do(func() { fe.cycleSearch(true) })},
+		{"recent", []string{"r"}, "Cycle through recently opened files", do(func() { fe.cycleRecent(false) })},
+		{"recent-backward", []string{"R"}, "Cycle backwards through recently opened files", do(func() { fe.cycleRecent(true) })},
+		{"quit-cd", []string{"S"}, "Quit and write the current directory for the shell to change to", do(fe.quitAndChangeDirectory)},
+		{"preview-down", []string{"J"}, "Scroll the preview down half a page", scrollPreview(func() int { return fe.preview.pageHeight() })},
+		{"preview-up", []string{"K"}, "Scroll the preview up half a page", scrollPreview(func() int { return -fe.preview.pageHeight() })},
+		{"preview-line-down", []string{"Ctrl-E"}, "Scroll the preview down one line", scrollPreview(func() int { return 1 })},
+		{"preview-line-up", []string{"Ctrl-Y"}, "Scroll the preview up one line", scrollPreview(func() int { return -1 })},
+		{"focus-preview", []string{"Tab"}, "Focus the preview to scroll and search in it", do(fe.focusPreview)},
+		{"yank", []string{"yy"}, "Yank the selected file", do(fe.yankCurrentFile)},
+		{"paste", []string{"pp"}, "Paste the yanked file", do(fe.pasteYankedFile)},
+		{"trash", []string{"dd"}, "Move the selected file to the trash", do(func() { fe.deleteCurrentFile(false) })},
+		{"delete", []string{"DD"}, "Permanently delete the selected file", do(func() { fe.deleteCurrentFile(true) })},
+		{"mark", []string{"mm", "M"}, "Toggle the mark of the selected file", do(fe.toggleMarkForCurrentFile)},
+		{"unmark-all", []string{"mu"}, "Unmark all files", do(fe.unmarkAllFiles)},
+		{"trash-marked", []string{"md"}, "Move the marked files to the trash", do(func() { fe.deleteMarkedFiles(false) })},
+		{"delete-marked", []string{"mD"}, "Permanently delete the marked files", do(func() { fe.deleteMarkedFiles(true) })},
+		{"yank-marked", []string{"my"}, "Yank the marked files", do(fe.yankMarkedFiles)},
+		{"paste-marked", []string{"mp"}, "Paste the marked files", do(fe.pasteMarkedFiles)},
+	}
+}
+
+// registerKeyActions registers the built-in actions as commands, along with
+// the ones taking arguments, and binds their default keys
+func (fe *FileExplorer) registerKeyActions() {
+	fe.defaultKeymap = map[string]string{"q": "quit"}
+	for _, action := range fe.keyActions() {
+		run := action.run
+		fe.commands.Register(&command.Command{
+			Name:        action.name,
+			Description: action.description + " (" + strings.Join(action.keys, ", ") + ")",
+			Run: func(args command.Args) error {
+				return run()
+			},
+		})
+		for _, keys := range action.keys {
+			fe.defaultKeymap[keys] = action.name
+		}
+	}
+	fe.commands.Register(&command.Command{
+		Name:        "anchor",
+		Args:        []command.Arg{{Name: "key"}},
+		Description: "Set an anchor for a key at the current directory (A<key>)",
+		Run: func(args command.Args) error {
+			fe.setAnchor(args.String("key"))
+			return nil
+		},
+	})
+	fe.commands.Register(&command.Command{
+		Name:        "jump",
+		Args:        []command.Arg{{Name: "key"}},
+		Description: "Jump to the anchor of a key (a<key>)",
+		Run: func(args command.Args) error {
+			fe.jumpToAnchor(args.String("key"))
+			return nil
+		},
+	})
+	fe.commands.Register(&command.Command{
+		Name:        "sort",
+		Args:        []command.Arg{{Name: "order", Optional: true, Choices: sortOrders}},
+		Description: "Sort the listings by an order, the next one without arguments",
+		Run: func(args command.Args) error {
+			order := args.String("order")
+			if order == "" {
+				index := slices.Index(sortOrders, fe.context.Config.Sort)
+				order = sortOrders[(index+1)%len(sortOrders)]
+			}
+			if err := fe.setConfigValue("sort", order); err != nil {
+				return err
+			}
+			fe.showMessage("sort=" + order)
+			return fe.refreshDirectory()
+		},
+	})
+}
+
+// handleKeymapKey runs the command bound to a key. Typed characters are
+// collected, so that they can form sequences like dd; special keys are
+// bound on their own.
+func (fe *FileExplorer) handleKeymapKey(name string, isRune bool, rune rune) {
+	if !isRune {
+		fe.keyBuffer = ""
+		if commandLine, isBound := fe.specialKeyCommand(name); isBound {
+			fe.runCommandLine(commandLine)
+		}
+		return
+	}
+	fe.keyBuffer = lastRunes(fe.keyBuffer+string(rune), fe.longestKeySequence())
+	sequence, commandLine := fe.keymapBinding(fe.keyBuffer)
+	// An anchor key takes precedence over a shorter binding of its key
+	if match := anchorRegexp.FindStringSubmatch(fe.keyBuffer); match != nil && len(match[0]) > len(sequence) {
+		fe.keyBuffer = ""
+		if match[1] == "A" {
+			fe.setAnchor(match[2])
+		} else {
+			fe.jumpToAnchor(match[2])
+		}
+		return
+	}
+	if sequence != "" {
+		fe.keyBuffer = ""
+		fe.runCommandLine(commandLine)
+	}
+}
+
+// keyName returns the name of a special key like Ctrl-D, the same whether or
+// not the terminal reports the Ctrl modifier of a control key
+func keyName(event *tcell.EventKey) string {
+	key := event.Key()
+	if key >= tcell.KeyCtrlSpace && key <= tcell.KeyCtrlUnderscore || key == tcell.KeyDEL {
+		return tcell.NewEventKey(key, event.Rune(), event.Modifiers()&^tcell.ModCtrl).Name()
+	}
+	return event.Name()
+}
+
+// specialKeyCommand returns the command line bound to a special key.
+// Bindings of the config take precedence over the default keys; an empty
+// command line unbinds the key.
+func (fe *FileExplorer) specialKeyCommand(name string) (string, bool) {
+	for _, keymap := range []map[string]string{fe.context.Config.Keymap, fe.defaultKeymap} {
+		for boundKeys, commandLine := range keymap {
+			// Modifiers may also be written like Ctrl+G
+			if strings.ReplaceAll(boundKeys, "+", "-") == name {
+				return commandLine, commandLine != ""
+			}
+		}
+	}
+	return "", false
+}
+
+// keymapBinding returns the longest bound key sequence that the typed keys
+// end with and its command line. Bindings of the config take precedence over
+// the default keys; an empty command line unbinds the keys.
+func (fe *FileExplorer) keymapBinding(keys string) (string, string) {
+	for _, keymap := range []map[string]string{fe.context.Config.Keymap, fe.defaultKeymap} {
+		sequence := ""
+		for boundKeys := range keymap {
+			if strings.HasSuffix(keys, boundKeys) && len(boundKeys) > len(sequence) {
+				sequence = boundKeys
+			}
+		}
+		if sequence != "" && keymap[sequence] == "" {
+			return "", ""
+		} else if sequence != "" {
+			return sequence, keymap[sequence]
+		}
+	}
+	return "", ""
+}
+
+// longestKeySequence returns the number of characters of the longest bound
+// key sequence, at least the two of an anchor key
+func (fe *FileExplorer) longestKeySequence() int {
+	length := 2
+	for _, keymap := range []map[string]string{fe.context.Config.Keymap, fe.defaultKeymap} {
+		for boundKeys := range keymap {
+			length = max(length, utf8.RuneCountInString(boundKeys))
+		}
+	}
+	return length
+}
+
+// lastRunes returns the last count characters of text
+func lastRunes(text string, count int) string {
+	runes := []rune(text)
+	return string(runes[max(len(runes)-count, 0):])
+}
+
+// openCurrentEntry enters the selected directory or opens the selected file
+func (fe *FileExplorer) openCurrentEntry() error {
+	_, fileName := fe.currentList.GetItemText(fe.currentList.GetCurrentItem())
+	filePath := filepath.Join(fe.context.CurrentPath, fileName)
+	fileInfo, err := os.Stat(filePath)
+	if err != nil {
+		return err
+	}
+	if fileInfo.IsDir() {
+		return fe.setCurrentDirectory(filePath)
+	}
+	fe.openFile(filePath)
+	return nil
+}
+
+// cycleSearch moves the cursor to the next or previous search match,
+// wrapping around at the end of the list
+func (fe *FileExplorer) cycleSearch(isBackward bool) {
+	if len(fe.currentSearchIndeces) == 0 {
+		return
+	}
+	currentIndex := fe.currentList.GetCurrentItem()
+	if isBackward {
+		for i := len(fe.currentSearchIndeces) - 1; i >= 0; i-- {
+			if fe.currentSearchIndeces[i] < currentIndex {
+				fe.setCurrentLine(fe.currentSearchIndeces[i])
+				return
+			}
+		}
+		fe.setCurrentLine(fe.currentSearchIndeces[len(fe.currentSearchIndeces)-1])
+		return
+	}
+	for _, index := range fe.currentSearchIndeces {
+		if index > currentIndex {
+			fe.setCurrentLine(index)
+			return
+		}
+	}
+	fe.setCurrentLine(fe.currentSearchIndeces[0])
+}
@@ -0,0 +1,386 @@
+package explorer
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/thilobro/gofileyourself/internal/command"
+	"github.com/thilobro/gofileyourself/internal/config"
+	"github.com/thilobro/gofileyourself/internal/helper"
+	"github.com/thilobro/gofileyourself/internal/rename"
+	"github.com/thilobro/gofileyourself/internal/theme"
+
+	"github.com/rivo/tview"
+)
+
+// registerCommands sets up the commands of the footer command line
+func (fe *FileExplorer) registerCommands() {
+	fe.commands = command.NewRegistry()
+	fe.registerKeyActions()
+	fe.commands.Register(&command.Command{
+		Name:        "quit",
+		Aliases:     []string{"q"},
+		Description: "Quit the file explorer",
+		Run: func(args command.Args) error {
+			fe.context.App.Stop()
+			return nil
+		},
+	})
+	fe.commands.Register(&command.Command{
+		Name:        "mkdir",
+		Args:        []command.Arg{{Name: "directory", Type: command.Path, Variadic: true}},
+		Description: "Create directories, including missing parents",
+		Run: func(args command.Args) error {
+			defer fe.setCurrentDirectory(fe.context.CurrentPath)
+			for _, directory := range args.Strings("directory") {
+				if err := helper.CreateDirectory(helper.GetAbsFilePath(directory, fe.context.CurrentPath)); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+	fe.commands.Register(&command.Command{
+		Name:        "touch",
+		Args:        []command.Arg{{Name: "file", Type: command.Path, Variadic: true}},
+		Description: "Create empty files",
+		Run: func(args command.Args) error {
+			defer fe.setCurrentDirectory(fe.context.CurrentPath)
+			for _, file := range args.Strings("file") {
+				if err := helper.TouchFile(helper.GetAbsFilePath(file, fe.context.CurrentPath)); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+	fe.commands.Register(&command.Command{
+		Name:        "rename",
+		Args:        []command.Arg{{Name: "name", Type: command.Path}},
+		Description: "Rename the selected file",
+		Run: func(args command.Args) error {
+			_, currentName := fe.currentList.GetItemText(fe.currentList.GetCurrentItem())
+			currentPath := filepath.Join(fe.context.CurrentPath, currentName)
+			plan, err := rename.NewPlan([]string{currentPath}, []string{args.String("name")})
+			if err != nil {
+				return err
+			}
+			defer fe.setCurrentDirectory(fe.context.CurrentPath)
+			return plan.Apply()
+		},
+	})
+	fe.commands.Register(&command.Command{
+		Name:        "mrename",
+		Description: "Bulk rename the marked files in the editor",
+		Run: func(args command.Args) error {
+			return fe.renameMarkedFiles()
+		},
+	})
+	fe.commands.Register(&command.Command{
+		Name:        "rename-pattern",
+		Args:        []command.Arg{{Name: "pattern", Type: command.Rest}},
+		Description: "Rename the marked files by s/regex/replacement/ or a template like {name}_{n:03}.{ext}",
+		Run: func(args command.Args) error {
+			return fe.renameByPattern(args.String("pattern"))
+		},
+	})
+	fe.commands.Register(&command.Command{
+		Name:    "edit",
+		Aliases: []string{"e"},
+		Args: []command.Arg{
+			{Name: "file", Type: command.Path, Optional: true},
+			{Name: "line[:column]", Optional: true},
+		},
+		Description: "Open the selected or given file in the editor, optionally at a position",
+		Run: func(args command.Args) error {
+			return fe.editFile(args.String("file"), args.String("line[:column]"))
+		},
+	})
+	fe.commands.Register(&command.Command{
+		Name:        "shell",
+		Aliases:     []string{"!"},
+		Args:        []command.Arg{{Name: "command", Type: command.Rest}},
+		Description: "Run a shell command, flags: -w wait for a key, -p capture output, -f detach; macros: %f %s %d %c",
+		Run: func(args command.Args) error {
+			return fe.runShellCommand(args.String("command"))
+		},
+	})
+	fe.commands.Register(&command.Command{
+		Name:        "set",
+		Args:        []command.Arg{{Name: "option", Optional: true, Variadic: true, Choices: config.Keys()}},
+		Description: "Change options: key=value, key / nokey / key! for switches, key? to show a value; all options without arguments",
+		Run: func(args command.Args) error {
+			if !args.Has("option") {
+				pager := fe.page("Options")
+				for _, key := range config.Keys() {
+					value, _ := fe.context.Config.Get(key)
+					pager.textView.Write([]byte(tview.Escape(key+"="+value) + "\n"))
+				}
+				return nil
+			}
+			defer fe.refreshDirectory()
+			for _, option := range args.Strings("option") {
+				if err := fe.setOption(option); err != nil {
+					return err
+				}
+			}
+			return fe.loadTheme()
+		},
+	})
+	fe.commands.Register(&command.Command{
+		Name:        "colorscheme",
+		Aliases:     []string{"colo"},
+		Args:        []command.Arg{{Name: "theme", Optional: true, Choices: theme.Names()}},
+		Description: "Switch to a theme; all themes without arguments",
+		Run: func(args command.Args) error {
+			if !args.Has("theme") {
+				pager := fe.page("Themes")
+				for _, name := range theme.Names() {
+					prefix := "  "
+					if name == theme.Current().Name {
+						prefix = "* "
+					}
+					pager.textView.Write([]byte(tview.Escape(prefix+name) + "\n"))
+				}
+				return nil
+			}
+			newTheme, err := theme.Load(args.String("theme"))
+			if err != nil {
+				return err
+			}
+			theme.SetCurrent(newTheme)
+			defer fe.refreshDirectory()
+			return fe.setConfigValue("theme", args.String("theme"))
+		},
+	})
+	fe.commands.Register(&command.Command{
+		Name:        "map",
+		Args:        []command.Arg{{Name: "keys command", Type: command.Rest, Optional: true}},
+		Description: "Bind keys like gd or Ctrl+G to a command line; all bindings, including the default ones, without arguments",
+		Run: func(args command.Args) error {
+			keys, commandLine, _ := strings.Cut(args.String("keys command"), " ")
+			if keys == "" {
+				pager := fe.page("Key bindings")
+				// The default keys follow the bindings of the config that
+				// do not replace them
+				bindings := maps.Clone(fe.defaultKeymap)
+				maps.Copy(bindings, fe.context.Config.Keymap)
+				// Unbound default keys are left out
+				maps.DeleteFunc(bindings, func(boundKeys string, commandLine string) bool {
+					return commandLine == ""
+				})
+				sortedKeys := []string{}
+				for boundKeys := range bindings {
+					sortedKeys = append(sortedKeys, boundKeys)
+				}
+				sort.SliceStable(sortedKeys, func(i, j int) bool {
+					_, isBoundI := fe.context.Config.Keymap[sortedKeys[i]]
+					_, isBoundJ := fe.context.Config.Keymap[sortedKeys[j]]
+					if isBoundI != isBoundJ {
+						return isBoundI
+					}
+					return sortedKeys[i] < sortedKeys[j]
+				})
+				for _, boundKeys := range sortedKeys {
+					pager.textView.Write([]byte(tview.Escape(boundKeys+"  "+bindings[boundKeys]) + "\n"))
+				}
+				return nil
+			}
+			commandLine = strings.TrimSpace(commandLine)
+			if commandLine == "" {
+				return errors.New("missing command for " + keys)
+			}
+			for _, cfg := range fe.changedConfigs() {
+				if cfg.Keymap == nil {
+					cfg.Keymap = make(map[string]string)
+				}
+				cfg.Keymap[keys] = commandLine
+			}
+			return nil
+		},
+	})
+	fe.commands.Register(&command.Command{
+		Name:        "unmap",
+		Args:        []command.Arg{{Name: "keys"}},
+		Description: "Remove a key binding, also of a default key",
+		Run: func(args command.Args) error {
+			keys := args.String("keys")
+			commandLine, isBound := fe.context.Config.Keymap[keys]
+			// Special keys may also be written like Ctrl+D
+			_, isDefault := fe.defaultKeymap[strings.ReplaceAll(keys, "+", "-")]
+			if isBound && commandLine == "" || !isBound && !isDefault {
+				return errors.New(keys + " is not bound")
+			}
+			for _, cfg := range fe.changedConfigs() {
+				if !isDefault {
+					delete(cfg.Keymap, keys)
+					continue
+				}
+				// An empty command line masks the default key
+				if cfg.Keymap == nil {
+					cfg.Keymap = make(map[string]string)
+				}
+				cfg.Keymap[keys] = ""
+			}
+			return nil
+		},
+	})
+	fe.commands.Register(&command.Command{
+		Name:        "trust",
+		Description: "Trust the local config files of the current directory and apply them",
+		Run: func(args command.Args) error {
+			if len(fe.untrustedConfigFiles) == 0 {
+				return errors.New("no untrusted local config files")
+			}
+			for _, file := range fe.untrustedConfigFiles {
+				if err := fe.context.GlobalConfig.Trust(file); err != nil {
+					return err
+				}
+			}
+			fe.applyLocalConfig(fe.context.CurrentPath, true)
+			return fe.refreshDirectory()
+		},
+	})
+	fe.commands.Register(&command.Command{
+		Name:        "untrust",
+		Description: "Stop applying the local config files of the current directory",
+		Run: func(args command.Args) error {
+			if len(fe.localConfigFiles) == 0 {
+				return errors.New("no local config files applied")
+			}
+			for _, file := range fe.localConfigFiles {
+				if err := fe.context.GlobalConfig.Untrust(file); err != nil {
+					return err
+				}
+			}
+			fe.applyLocalConfig(fe.context.CurrentPath, true)
+			return fe.refreshDirectory()
+		},
+	})
+	fe.commands.Register(&command.Command{
+		Name:        "help",
+		Args:        []command.Arg{{Name: "command", Type: command.CommandName, Optional: true}},
+		Description: "List all commands or show the usage of one",
+		Run: func(args command.Args) error {
+			lines, err := fe.commands.Help(args.String("command"))
+			if err != nil {
+				return err
+			}
+			pager := fe.page("Help")
+			for _, line := range lines {
+				pager.textView.Write([]byte(tview.Escape(line) + "\n"))
+			}
+			return nil
+		},
+	})
+}
+
+// setOption applies a single :set argument
+func (fe *FileExplorer) setOption(option string) error {
+	cfg := fe.context.Config
+	if key, value, isAssignment := strings.Cut(option, "="); isAssignment {
+		// An unknown theme is not stored, so that it does not fail every
+		// later :set
+		if key == "theme" {
+			if _, err := theme.Load(value); err != nil {
+				return err
+			}
+		}
+		return fe.setConfigValue(key, value)
+	}
+	if key, isQuery := strings.CutSuffix(option, "?"); isQuery {
+		value, err := cfg.Get(key)
+		if err != nil {
+			return err
+		}
+		fe.showMessage(key + "=" + value)
+		return nil
+	}
+	if key, isToggle := strings.CutSuffix(option, "!"); isToggle && config.IsBool(key) {
+		value, _ := cfg.Get(key)
+		return fe.setConfigValue(key, strconv.FormatBool(value != "true"))
+	}
+	if config.IsBool(option) {
+		return fe.setConfigValue(option, "true")
+	}
+	if key, isNegation := strings.CutPrefix(option, "no"); isNegation && config.IsBool(key) {
+		return fe.setConfigValue(key, "false")
+	}
+	return fe.setOption(option + "?")
+}
+
+// setConfigValue changes an option in the configs of changedConfigs
+func (fe *FileExplorer) setConfigValue(key string, value string) error {
+	for _, cfg := range fe.changedConfigs() {
+		if err := cfg.Set(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// changedConfigs returns the configs that changes at runtime go to: the
+// effective config, and the global one unless local configs are applied
+func (fe *FileExplorer) changedConfigs() []*config.Config {
+	if len(fe.localConfigFiles) > 0 {
+		return []*config.Config{fe.context.Config}
+	}
+	return []*config.Config{fe.context.Config, fe.context.GlobalConfig}
+}
+
+// registerUserCommands adds the commands defined in the config. They must not
+// replace a built-in command.
+func (fe *FileExplorer) registerUserCommands() error {
+	problems := []string{}
+	for _, userCommand := range fe.context.Config.Commands {
+		if userCommand.Name == "" || len(userCommand.Run) == 0 {
+			problems = append(problems, fmt.Sprintf("%q needs a name and a run entry", userCommand.Name))
+			continue
+		}
+		if name := fe.firstDefinedName(append([]string{userCommand.Name}, userCommand.Aliases...)); name != "" {
+			problems = append(problems, name+" is already defined")
+			continue
+		}
+		description := userCommand.Description
+		if description == "" {
+			description = strings.Join(userCommand.Run, "; ")
+		}
+		commandLines := userCommand.Run
+		fe.commands.Register(&command.Command{
+			Name:        userCommand.Name,
+			Aliases:     userCommand.Aliases,
+			Description: description,
+			Run: func(args command.Args) error {
+				if fe.commandDepth >= MAX_COMMAND_DEPTH {
+					return errors.New("too many nested commands")
+				}
+				fe.commandDepth++
+				defer func() { fe.commandDepth-- }()
+				for _, commandLine := range commandLines {
+					if err := fe.commands.Execute(strings.TrimPrefix(commandLine, ":")); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		})
+	}
+	if len(problems) > 0 {
+		return errors.New("commands: " + strings.Join(problems, ", "))
+	}
+	return nil
+}
+
+func (fe *FileExplorer) firstDefinedName(names []string) string {
+	for _, name := range names {
+		if _, exists := fe.commands.Lookup(name); exists {
+			return name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,98 @@
+package explorer
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/thilobro/gofileyourself/internal/command"
+)
+
+// completeCommandLine returns the command lines that the last word of text
+// can be completed to: command names first, then paths or command names
+// depending on the argument that is being typed
+func (fe *FileExplorer) completeCommandLine(text string) []string {
+	name, _ := command.SplitName(text)
+	if name == text {
+		candidates := []string{}
+		for _, commandName := range fe.commands.Names() {
+			if strings.HasPrefix(commandName, name) {
+				candidates = append(candidates, commandName+" ")
+			}
+		}
+		return candidates
+	}
+	cmd, exists := fe.commands.Lookup(name)
+	if !exists || len(cmd.Args) == 0 {
+		return nil
+	}
+
+	before, word := command.LastWord(text)
+	argIndex := 0
+	if tokens, err := command.Tokenize(strings.TrimPrefix(strings.TrimLeft(before, " "), name)); err == nil {
+		argIndex = len(tokens)
+	}
+	arg := cmd.Args[len(cmd.Args)-1]
+	if argIndex < len(cmd.Args) {
+		arg = cmd.Args[argIndex]
+	} else if !arg.Variadic && arg.Type != command.Rest {
+		return nil
+	}
+
+	var values []string
+	for _, choice := range arg.Choices {
+		if strings.HasPrefix(choice, word) {
+			values = append(values, choice)
+		}
+	}
+	switch arg.Type {
+	case command.Path, command.Rest:
+		values = fe.completePath(word)
+	case command.CommandName:
+		for _, commandName := range fe.commands.Names() {
+			if strings.HasPrefix(commandName, word) {
+				values = append(values, commandName)
+			}
+		}
+	}
+	candidates := make([]string, len(values))
+	for i, value := range values {
+		candidates[i] = before + command.Escape(value)
+	}
+	return candidates
+}
+
+// completePath lists the entries of the directory of a partial path that
+// start with its base name. Directories end in a slash so that completion can
+// continue inside them.
+func (fe *FileExplorer) completePath(partial string) []string {
+	dirPart, basePart := filepath.Split(partial)
+	dir := dirPart
+	if strings.HasPrefix(dir, "~/") {
+		homeDir, _ := os.UserHomeDir()
+		dir = filepath.Join(homeDir, dir[2:])
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(fe.context.CurrentPath, dir)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	showHiddenFiles := fe.context.Config.ShowHidden || strings.HasPrefix(basePart, ".")
+	candidates := []string{}
+	for _, entry := range entries {
+		entryName := entry.Name()
+		if !strings.HasPrefix(entryName, basePart) || (!showHiddenFiles && strings.HasPrefix(entryName, ".")) {
+			continue
+		}
+		candidate := dirPart + entryName
+		if info, err := os.Stat(filepath.Join(dir, entryName)); err == nil && info.IsDir() {
+			candidate += "/"
+		}
+		candidates = append(candidates, candidate)
+	}
+	sort.Strings(candidates)
+	return candidates
+}
@@ -0,0 +1,146 @@
+package explorer
+
+import (
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/thilobro/gofileyourself/internal/config"
+	"github.com/thilobro/gofileyourself/internal/helper"
+	"github.com/thilobro/gofileyourself/internal/theme"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// confirmDialog is a modal window that shows what an action is going to do
+// and runs it only after the user accepted
+type confirmDialog struct {
+	root      tview.Primitive
+	textView  *tview.TextView
+	onConfirm func()
+	onCancel  func()
+}
+
+func newConfirmDialog(title string, lines []string, onConfirm func(), onCancel func()) *confirmDialog {
+	explorerTheme := theme.Current()
+	textView := tview.NewTextView().
+		SetDynamicColors(true).
+		SetScrollable(true).
+		SetWrap(false)
+	textView.SetBorder(true).
+		SetTitle(" " + title + " ").
+		SetBorderColor(explorerTheme.Danger).
+		SetTitleColor(explorerTheme.Danger).
+		SetBackgroundColor(explorerTheme.Panel)
+	textView.SetTextColor(explorerTheme.TextStrong)
+
+	text := ""
+	for _, line := range lines {
+		text += line + "\n"
+	}
+	text += "\n[::b]y[::-]/Enter confirm   [::b]n[::-]/Esc cancel   j/k scroll"
+	textView.SetText(text)
+
+	return &confirmDialog{
+		root:      centerDialog(textView, len(lines)+4),
+		textView:  textView,
+		onConfirm: onConfirm,
+		onCancel:  onCancel,
+	}
+}
+
+func (dialog *confirmDialog) Root() tview.Primitive {
+	return dialog.root
+}
+
+func (dialog *confirmDialog) Focus() tview.Primitive {
+	return dialog.textView
+}
+
+func (dialog *confirmDialog) InputCapture(event *tcell.EventKey) *tcell.EventKey {
+	switch event.Key() {
+	case tcell.KeyEnter:
+		dialog.onConfirm()
+		return nil
+	case tcell.KeyEscape:
+		dialog.onCancel()
+		return nil
+	}
+	switch event.Rune() {
+	case 'y', 'Y':
+		dialog.onConfirm()
+	case 'n', 'N', 'q':
+		dialog.onCancel()
+	case 'j':
+		row, _ := dialog.textView.GetScrollOffset()
+		dialog.textView.ScrollTo(row+1, 0)
+	case 'k':
+		row, _ := dialog.textView.GetScrollOffset()
+		if row > 0 {
+			dialog.textView.ScrollTo(row-1, 0)
+		}
+	}
+	return nil
+}
+
+// confirm shows a confirmation dialog and runs onConfirm once accepted
+func (fe *FileExplorer) confirm(title string, lines []string, onConfirm func()) {
+	fe.dialog = newConfirmDialog(title, lines, func() {
+		fe.dialog = nil
+		onConfirm()
+		fe.Draw()
+	}, func() {
+		fe.dialog = nil
+		fe.Draw()
+	})
+	fe.Draw()
+}
+
+// confirmForPaths asks for confirmation according to the configured mode of
+// the action before running it on the given paths. The sizes shown in the
+// dialog are only computed when it is shown.
+func (fe *FileExplorer) confirmForPaths(title string, mode string, paths []string, onConfirm func()) {
+	if len(paths) == 0 {
+		return
+	}
+	switch mode {
+	case config.ConfirmNever:
+		onConfirm()
+		return
+	case config.ConfirmRecursive:
+		if !slices.ContainsFunc(paths, isNonEmptyDirectory) {
+			onConfirm()
+			return
+		}
+	}
+
+	totalFiles, totalDirs, totalSize := 0, 0, int64(0)
+	lines := []string{}
+	for _, path := range paths {
+		files, dirs, size := helper.DiskUsage(path)
+		totalFiles += files
+		totalDirs += dirs
+		totalSize += size
+		if info, err := os.Lstat(path); err == nil && info.IsDir() {
+			lines = append(lines, fmt.Sprintf("%s/  %s(%d files, %d dirs, %s)[-::]",
+				tview.Escape(path), theme.Tag(theme.Current().Muted, ""), files, dirs-1, helper.FormatSize(size)))
+		} else {
+			lines = append(lines, fmt.Sprintf("%s  %s(%s)[-::]", tview.Escape(path), theme.Tag(theme.Current().Muted, ""), helper.FormatSize(size)))
+		}
+	}
+	lines = append(lines, "", fmt.Sprintf("[::b]Total: %d items, %d files, %d dirs, %s[::-]",
+		len(paths), totalFiles, totalDirs, helper.FormatSize(totalSize)))
+	fe.confirm(title, lines, onConfirm)
+}
+
+// isNonEmptyDirectory reports whether path is a directory with contents,
+// not following symlinks
+func isNonEmptyDirectory(path string) bool {
+	info, err := os.Lstat(path)
+	if err != nil || !info.IsDir() {
+		return false
+	}
+	isEmpty, _ := helper.IsDirectoryEmpty(path)
+	return !isEmpty
+}
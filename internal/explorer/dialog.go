@@ -0,0 +1,30 @@
+package explorer
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// dialog is a modal window drawn on top of the explorer that receives all
+// key events while it is open
+type dialog interface {
+	Root() tview.Primitive
+	Focus() tview.Primitive
+	InputCapture(event *tcell.EventKey) *tcell.EventKey
+}
+
+// centerDialog centers a dialog on the screen. Dialogs higher than
+// MAX_DIALOG_HEIGHT take most of the screen and scroll.
+func centerDialog(content tview.Primitive, height int) tview.Primitive {
+	column := tview.NewFlex().SetDirection(tview.FlexRow).AddItem(nil, 0, 1, false)
+	if height <= MAX_DIALOG_HEIGHT {
+		column.AddItem(content, height, 0, true)
+	} else {
+		column.AddItem(content, 0, 8, true)
+	}
+	column.AddItem(nil, 0, 1, false)
+	return tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(column, 0, 4, true).
+		AddItem(nil, 0, 1, false)
+}
@@ -1,28 +1,44 @@
 package explorer
 
 import (
-	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"regexp"
 	"slices"
+	"strconv"
 	"strings"
 
+	"github.com/thilobro/gofileyourself/internal/command"
+	"github.com/thilobro/gofileyourself/internal/config"
+	"github.com/thilobro/gofileyourself/internal/editor"
 	"github.com/thilobro/gofileyourself/internal/formatter"
 	"github.com/thilobro/gofileyourself/internal/helper"
+	"github.com/thilobro/gofileyourself/internal/opener"
+	"github.com/thilobro/gofileyourself/internal/paths"
+	"github.com/thilobro/gofileyourself/internal/picture"
+	"github.com/thilobro/gofileyourself/internal/prompt"
+	"github.com/thilobro/gofileyourself/internal/rename"
+	"github.com/thilobro/gofileyourself/internal/shell"
 	"github.com/thilobro/gofileyourself/internal/theme"
+	"github.com/thilobro/gofileyourself/internal/trash"
 	"github.com/thilobro/gofileyourself/internal/widget"
 
-	gostring "github.com/boyter/go-string"
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
 )
 
 const (
 	MAX_SCROLL_AMOUNT = 20
+	MAX_DIALOG_HEIGHT = 20
+	// MAX_COMMAND_DEPTH stops user commands that call each other endlessly
+	MAX_COMMAND_DEPTH = 16
 )
 
+var positionRegexp = regexp.MustCompile(`^\+?(\d+)(?::(\d+))?$`)
+
 func init() {
 	formatter.RegisterCustomFormatter()
 }
@@ -44,10 +60,26 @@ type FileExplorer struct {
 	currentSearchIndeces []int
 	currentFocusedWidget tview.Primitive
 	keyBuffer            string
-	yankedFile           string
-	markedFiles          []string
-	yankedMarkedFiles    []string
-	cycleRecentPosition  int
+	// defaultKeymap binds the default keys to the built-in actions
+	defaultKeymap       map[string]string
+	yankedFile          string
+	markedFiles         []string
+	yankedMarkedFiles   []string
+	cycleRecentPosition int
+	dialog              dialog
+	commands            *command.Registry
+	promptHistory       *prompt.History
+	commandDepth        int
+	currentEntries      []helper.Entry
+	preview             *filePreview
+	isPreviewFocused    bool
+	// previewOffsets keeps the scroll offset of previewed files by path
+	previewOffsets map[string]int
+	// pendingSelectedName is selected in the previewed directory once it is
+	// loaded
+	pendingSelectedName  string
+	localConfigFiles     []string
+	untrustedConfigFiles []string
 }
 
 func (fe *FileExplorer) Root() tview.Primitive {
@@ -55,56 +87,66 @@ func (fe *FileExplorer) Root() tview.Primitive {
 }
 
 func (fe *FileExplorer) applyTheme() {
-	explorerTheme := theme.GetExplorerTheme()
+	explorerTheme := theme.Current()
 
 	// Set global background through root flex
-	fe.rootFlex.SetBackgroundColor(explorerTheme.Bg0)
-	fe.listFlex.SetBackgroundColor(explorerTheme.Bg0)
+	fe.rootFlex.SetBackgroundColor(explorerTheme.Background)
+	fe.listFlex.SetBackgroundColor(explorerTheme.Background)
+
+	if fe.header != nil {
+		fe.header.
+			SetTextColor(explorerTheme.Header).
+			SetTitleColor(explorerTheme.Header).
+			SetBorderColor(explorerTheme.Muted).
+			SetBackgroundColor(explorerTheme.Background)
+	}
 
 	// Style the lists
 	fe.currentList.
-		SetMainTextColor(explorerTheme.Fg1).
-		SetSelectedTextColor(explorerTheme.Black).
-		SetSelectedBackgroundColor(explorerTheme.Aqua).
-		SetBackgroundColor(explorerTheme.Bg0)
+		SetMainTextColor(explorerTheme.Text).
+		SetSelectedStyle(theme.SelectedStyle(explorerTheme.Selection)).
+		SetBackgroundColor(explorerTheme.Background)
 
 	if fe.parentList != nil {
 		if list, ok := fe.parentList.(*tview.List); ok {
 			list.
-				SetMainTextColor(explorerTheme.Fg1).
-				SetSelectedTextColor(explorerTheme.Black).
-				SetSelectedBackgroundColor(explorerTheme.Blue).
-				SetBackgroundColor(explorerTheme.Bg0)
+				SetMainTextColor(explorerTheme.Text).
+				SetSelectedStyle(theme.SelectedStyle(explorerTheme.SelectionParent)).
+				SetBackgroundColor(explorerTheme.Background)
 		}
 	}
 
 	// Style the selected list/preview
 	if list, ok := fe.selectedList.(*tview.List); ok {
 		list.
-			SetMainTextColor(explorerTheme.Fg1).
-			SetSelectedTextColor(explorerTheme.Black).
-			SetSelectedBackgroundColor(explorerTheme.Green).
-			SetBackgroundColor(explorerTheme.Bg0)
+			SetMainTextColor(explorerTheme.Text).
+			SetSelectedStyle(theme.SelectedStyle(explorerTheme.SelectionPreview)).
+			SetBackgroundColor(explorerTheme.Background)
+	} else if view, ok := fe.selectedList.(*picture.View); ok {
+		view.SetBackgroundColor(explorerTheme.Background)
 	} else if textView, ok := fe.selectedList.(*tview.TextView); ok {
+		background := explorerTheme.Background
+		if fe.isPreviewFocused {
+			background = explorerTheme.Panel
+		}
 		textView.
-			SetTextColor(explorerTheme.Fg0).
-			SetBackgroundColor(explorerTheme.Bg0)
+			SetTextColor(explorerTheme.TextStrong).
+			SetBackgroundColor(background)
 	}
 
 	// Style the footer
 	if fe.footer != nil {
 		fe.footer.
-			SetFieldBackgroundColor(explorerTheme.Bg1).
-			SetFieldTextColor(explorerTheme.Fg0).
-			SetBackgroundColor(explorerTheme.Bg0)
+			SetFieldBackgroundColor(explorerTheme.Panel).
+			SetFieldTextColor(explorerTheme.TextStrong).
+			SetLabelColor(explorerTheme.TextStrong).
+			SetBackgroundColor(explorerTheme.Background)
 	}
 }
 
 func (fe *FileExplorer) highlightSearchInput() {
-	for i := 0; i < fe.currentList.GetItemCount(); i++ {
-		_, text := fe.currentList.GetItemText(i)
-		indeces := gostring.IndexAll(text, fe.searchInput, -1)
-		fe.currentList.SetItemText(i, gostring.HighlightString(text, indeces, "[red::b]", "[-::-]"), text)
+	for i, entry := range fe.currentEntries {
+		fe.currentList.SetItemText(i, entry.Text(fe.searchInput), entry.Name)
 	}
 }
 
@@ -116,6 +158,7 @@ func NewFileExplorer(context *widget.Context) (*FileExplorer, error) {
 		parentList:          tview.NewList(),
 		selectedList:        tview.NewList(),
 		directoryToIndexMap: make(map[string]int),
+		previewOffsets:      make(map[string]int),
 		listFlex:            tview.NewFlex(),
 		rootFlex:            tview.NewFlex(),
 		footer:              tview.NewInputField(),
@@ -129,6 +172,8 @@ func NewFileExplorer(context *widget.Context) (*FileExplorer, error) {
 		yankedMarkedFiles:   []string{},
 		cycleRecentPosition: 0,
 	}
+	fe.registerCommands()
+	fe.promptHistory = prompt.LoadHistory(context.Config.Paths.PromptHistory, context.Config.PromptHistoryLen)
 
 	if err := fe.initialize(); err != nil {
 		return nil, err
@@ -177,44 +222,61 @@ func (fe *FileExplorer) Draw() {
 	if fe.footer != nil {
 		fe.rootFlex.AddItem(fe.footer, 1, 0, false)
 	}
-	fe.context.App.SetRoot(fe.rootFlex, true)
-	fe.context.App.SetFocus(fe.currentFocusedWidget)
+	if fe.dialog != nil {
+		pages := tview.NewPages().
+			AddPage("explorer", fe.rootFlex, true, true).
+			AddPage("dialog", fe.dialog.Root(), true, true)
+		fe.context.App.SetRoot(pages, true)
+		fe.context.App.SetFocus(fe.dialog.Focus())
+	} else {
+		fe.context.App.SetRoot(fe.rootFlex, true)
+		fe.context.App.SetFocus(fe.currentFocusedWidget)
+	}
 	fe.applyTheme()
 	fe.highlightSearchInput()
 }
 
 func (fe *FileExplorer) GetInputCapture() func(*tcell.EventKey) *tcell.EventKey {
+	if fe.dialog != nil {
+		return fe.dialog.InputCapture
+	}
 	if fe.isFooterActive && fe.footer != nil {
 		return fe.footer.GetInputCapture()
 	}
+	if fe.isPreviewFocused {
+		return fe.previewInputCapture
+	}
 	return fe.currentList.GetInputCapture()
 }
 
-// setSelectedDirectory updates the selected directory/file preview
+// setSelectedDirectory updates the selected directory/file preview. The
+// preview is generated in the background.
 func (fe *FileExplorer) setSelectedDirectory(selectedPath string) error {
-	selectedAbsolutePath, _ := filepath.Abs(selectedPath)
-	isDirEmpty, _ := helper.IsDirectoryEmpty(selectedAbsolutePath)
-	if isDirEmpty {
-		fe.selectedList = tview.NewTextArea().SetText("Directory is empty", false)
+	fe.closePreview()
+	if !fe.context.Config.Preview.Enabled {
+		if previewLoader != nil {
+			previewLoader.Cancel()
+		}
+		fe.selectedList = nil
 		return nil
 	}
-	selectedDirectoryIndex := fe.directoryToIndexMap[selectedAbsolutePath]
+	selectedAbsolutePath, _ := filepath.Abs(selectedPath)
+	fe.loadSelected(selectedAbsolutePath)
+	return nil
+}
 
-	newSelectedList, err := helper.LoadDirectory(selectedPath, fe.context.ShowHiddenFiles, false, fe.markedFiles)
-	if err != nil {
-		return err
+// closePreview remembers the scroll offset of the file preview before it is
+// replaced
+func (fe *FileExplorer) closePreview() {
+	if fe.preview == nil {
+		return
 	}
-
-	if newSelectedList == nil {
-		fe.selectedList, err = helper.LoadFilePreview(selectedPath)
-		if err != nil {
-			return err
-		}
-	} else {
-		newSelectedList.SetCurrentItem(selectedDirectoryIndex)
-		fe.selectedList = newSelectedList
+	row, _ := fe.preview.textView.GetScrollOffset()
+	fe.previewOffsets[fe.preview.path] = row
+	fe.preview = nil
+	if fe.isPreviewFocused {
+		fe.unfocusPreview()
 	}
-	return nil
 }
 
 func (fe *FileExplorer) setParentDirectory(path string) error {
@@ -224,7 +286,7 @@ func (fe *FileExplorer) setParentDirectory(path string) error {
 		fe.parentList = emptyList
 	} else {
 		parentPath := filepath.Join(currentAbsolutePath, "..")
-		newParentList, err := helper.LoadDirectory(parentPath, fe.context.ShowHiddenFiles, false, fe.markedFiles)
+		newParentList, err := helper.LoadDirectory(parentPath, helper.NewListOptions(fe.context.Config), false, fe.markedFiles)
 		if err != nil {
 			return err
 		}
@@ -251,17 +313,20 @@ func (fe *FileExplorer) setCurrentDirectory(path string) error {
 
 	// Update current directory
 	currentAbsolutePath, _ := filepath.Abs(path)
+	fe.applyLocalConfig(currentAbsolutePath, false)
 	currentDirectoryIndex := fe.directoryToIndexMap[currentAbsolutePath]
-	newCurrentList, err := helper.LoadDirectory(currentAbsolutePath, fe.context.ShowHiddenFiles, false, fe.markedFiles)
+	entries, err := helper.ListEntries(currentAbsolutePath, helper.NewListOptions(fe.context.Config), false, fe.markedFiles)
 	if err != nil {
 		return err
 	}
+	newCurrentList := helper.NewEntryList(entries)
 
 	newCurrentList.SetInputCapture(fe.currentList.GetInputCapture())
 	newCurrentList.SetCurrentItem(currentDirectoryIndex)
 	currentDirectoryIndex = newCurrentList.GetCurrentItem()
 	// update index in case it was clipped
 	fe.currentList = newCurrentList
+	fe.currentEntries = entries
 
 	// Update parent directory
 	fe.setParentDirectory(currentAbsolutePath)
@@ -282,6 +347,100 @@ func (fe *FileExplorer) setCurrentDirectory(path string) error {
 	return nil
 }
 
+// refreshDirectory reloads the current directory, e.g. after the listing
+// options changed, and keeps the current and previewed selections
+func (fe *FileExplorer) refreshDirectory() error {
+	_, currentName := fe.currentList.GetItemText(fe.currentList.GetCurrentItem())
+
+	var selectedName string
+	if list, ok := fe.selectedList.(*tview.List); ok {
+		_, selectedName = list.GetItemText(list.GetCurrentItem())
+	}
+
+	// The options of the cached previews may have changed
+	if previewLoader != nil {
+		previewLoader.Clear()
+	}
+	if err := fe.setCurrentDirectory(fe.context.CurrentPath); err != nil {
+		return err
+	}
+
+	fe.pendingSelectedName = selectedName
+	if idx := helper.FindExactItem(fe.currentList, currentName); idx >= 0 {
+		fe.setCurrentLine(idx)
+	}
+	return nil
+}
+
+// ReloadConfig applies a config that was changed on disk
+func (fe *FileExplorer) ReloadConfig(err error) {
+	defer fe.Draw()
+	if err != nil {
+		fe.showMessage("config: " + err.Error())
+		return
+	}
+	fe.applyLocalConfig(fe.context.CurrentPath, true)
+	fe.refreshDirectory()
+}
+
+// applyLocalConfig merges the trusted local config files of dir over the
+// global config, unless the same files are applied already. Forcing it also
+// looks up the parents of dir again.
+func (fe *FileExplorer) applyLocalConfig(dir string, force bool) {
+	globalConfig := fe.context.GlobalConfig
+	if force {
+		config.ClearLocalLookups()
+	}
+	files := []string{}
+	untrustedFiles := []string{}
+	if globalConfig.LocalConfig.Enabled {
+		for _, file := range config.FindLocal(dir) {
+			if globalConfig.IsTrusted(file) {
+				files = append(files, file)
+			} else {
+				untrustedFiles = append(untrustedFiles, file)
+			}
+		}
+	}
+	isApplied := fe.localConfigFiles != nil && slices.Equal(files, fe.localConfigFiles) && slices.Equal(untrustedFiles, fe.untrustedConfigFiles)
+	if isApplied && !force {
+		return
+	}
+	fe.localConfigFiles = files
+	fe.untrustedConfigFiles = untrustedFiles
+
+	mergedConfig, err := globalConfig.Merge(files)
+	*fe.context.Config = *mergedConfig
+	if previewLoader != nil {
+		previewLoader.Clear()
+	}
+	fe.registerCommands()
+	commandsErr := fe.registerUserCommands()
+	themeErr := fe.loadTheme()
+	if err != nil {
+		fe.showMessage("config: " + err.Error())
+	} else if commandsErr != nil {
+		fe.showMessage(commandsErr.Error())
+	} else if themeErr != nil {
+		fe.showMessage(themeErr.Error())
+	} else if len(untrustedFiles) > 0 {
+		fe.showMessage(untrustedFiles[len(untrustedFiles)-1] + " is not trusted, :trust applies it")
+	}
+}
+
+// loadTheme switches to the theme of the config unless it is active already
+func (fe *FileExplorer) loadTheme() error {
+	if fe.context.Config.Theme == theme.Current().Name {
+		return nil
+	}
+	newTheme, err := theme.Load(fe.context.Config.Theme)
+	if err != nil {
+		return err
+	}
+	theme.SetCurrent(newTheme)
+	return nil
+}
+
 func (fe *FileExplorer) setHeader(text string) {
 	fe.header.SetBorder(true).SetTitle("Explore").Blur()
 	fe.header.SetText(text)
@@ -307,74 +466,61 @@ func (fe *FileExplorer) searchInCurrentDirectory() {
 	if fe.currentSearchTerm == "" {
 		return
 	}
-	fe.currentSearchIndeces = fe.currentList.FindItems(fe.currentSearchTerm, "", false, true)
+	fe.currentSearchIndeces = fe.currentList.FindItems("", fe.currentSearchTerm, false, true)
 }
 
 func (fe *FileExplorer) runFooterCommand(inputText string) {
 	switch inputText[0] {
 	case '/':
+		if fe.isPreviewFocused {
+			fe.preview.setSearch(inputText[1:])
+			return
+		}
 		fe.currentSearchTerm = inputText[1:]
 		fe.searchInCurrentDirectory()
 		if len(fe.currentSearchIndeces) > 0 {
 			fe.setCurrentLine(fe.currentSearchIndeces[0])
 		}
 	case ':':
-		command := inputText[1:]
-		parts := strings.Split(command, " ")
-		switch parts[0] {
-		case "q":
-			fe.context.App.Stop()
-		case "mkdir":
-			if len(parts) > 1 {
-				helper.CreateDirectory(filepath.Join(fe.context.CurrentPath, parts[1]))
-				fe.setCurrentDirectory(fe.context.CurrentPath)
-			}
-		case "rename":
-			if len(parts) > 1 {
-				_, currentName := fe.currentList.GetItemText(fe.currentList.GetCurrentItem())
-				currentPath := filepath.Join(fe.context.CurrentPath, currentName)
-				helper.RenameFile(currentPath, parts[1])
-				fe.setCurrentDirectory(fe.context.CurrentPath)
-			}
-		case "mrename":
-			fe.renameMarkedFiles()
-		case "touch":
-			if len(parts) > 1 {
-				helper.TouchFile(filepath.Join(fe.context.CurrentPath, parts[1]))
-				fe.setCurrentDirectory(fe.context.CurrentPath)
-			}
-		}
+		fe.runCommandLine(inputText[1:])
+	}
+	if !fe.isPreviewFocused {
+		fe.currentFocusedWidget = fe.currentList
 	}
-	fe.currentFocusedWidget = fe.currentList
 }
 
-func (fe *FileExplorer) handleFooterInput(prompt string) {
+// runCommandLine executes a command line with an optional leading colon and
+// shows its error in the footer
+func (fe *FileExplorer) runCommandLine(commandLine string) {
+	if err := fe.commands.Execute(strings.TrimPrefix(commandLine, ":")); err != nil {
+		fe.showMessage(err.Error())
+	}
+}
+
+func (fe *FileExplorer) handleFooterInput(label string) {
 	fe.isFooterActive = true
-	fe.footer = tview.NewInputField().SetText(prompt)
-	fe.footer.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-		return event
-	})
-	fe.footer.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-		currentText := fe.footer.GetText()
-		if event.Key() == tcell.KeyBackspace2 {
-			currentTextLen := len(currentText)
-			if currentTextLen <= 1 {
-				return nil
-			}
-			currentText = currentText[:currentTextLen-1]
-		} else if event.Key() == tcell.KeyEnter {
-			return event
-		} else {
-			currentText = currentText + string(event.Rune())
-		}
-		fe.footer.SetText(currentText)
-		return nil
-	})
+	var completer prompt.Completer
+	if label == ":" {
+		completer = fe.completeCommandLine
+	}
+	footerPrompt := prompt.New(label, fe.promptHistory, completer)
+	fe.footer = footerPrompt.InputField
 	fe.footer.SetDoneFunc(
 		func(key tcell.Key) {
 			if key == tcell.KeyEnter {
-				inputText := fe.footer.GetText()
-				fe.runFooterCommand(inputText)
+				fe.runFooterCommand(footerPrompt.Line())
+			} else if key == tcell.KeyEscape && fe.isPreviewFocused {
+				fe.footer.SetText("")
+				if label == "/" {
+					fe.preview.setSearch("")
+				}
+			} else if key == tcell.KeyEscape {
+				fe.footer.SetText("")
+				fe.setCurrentLine(fe.currentList.GetCurrentItem())
+			}
+			if fe.isPreviewFocused {
+				fe.currentFocusedWidget = fe.preview.textView
+			} else {
 				fe.currentFocusedWidget = fe.currentList
 			}
 			fe.Draw()
@@ -384,16 +530,161 @@ func (fe *FileExplorer) handleFooterInput(prompt string) {
 	fe.footer.SetChangedFunc(
 		func(text string) {
 			defer fe.Draw()
-			fe.searchInput = strings.TrimPrefix(text, "/")
+			if label == "/" && fe.isPreviewFocused {
+				fe.preview.setSearch(text)
+			} else if label == "/" {
+				fe.searchInput = text
+			}
+			if expression, found := strings.CutPrefix(text, "rename-pattern "); found && label == ":" {
+				fe.selectedList = fe.renamePatternPreview(expression)
+			}
 		},
 	)
 	fe.currentFocusedWidget = fe.footer
 	fe.Draw()
 }
 
+func (fe *FileExplorer) openFile(filePath string) {
+	if err := opener.OpenDefault(filePath, fe.context.ChooseFilePath, fe.context.App, fe.context.Config); err != nil {
+		fe.showMessage("open: " + err.Error())
+	}
+}
+
+// openCurrentFileWith lists every opener rule that matches the current file
+func (fe *FileExplorer) openCurrentFileWith() {
+	_, currentName := fe.currentList.GetItemText(fe.currentList.GetCurrentItem())
+	filePath := filepath.Join(fe.context.CurrentPath, currentName)
+	if info, err := os.Stat(filePath); err != nil || info.IsDir() {
+		return
+	}
+	rules := []config.OpenerRule{}
+	labels := []string{}
+	for _, rule := range opener.Match(opener.Rules(fe.context.Config.Openers), opener.NewFile(filePath)) {
+		label := opener.Label(rule)
+		if slices.Contains(labels, label) {
+			continue
+		}
+		rules = append(rules, rule)
+		labels = append(labels, label)
+	}
+	fe.choose("Open with", labels, func(index int) {
+		if err := opener.OpenFile(filePath, rules[index], fe.context.App, fe.context.Config); err != nil {
+			fe.showMessage("open: " + err.Error())
+		}
+	})
+}
+
+// shellMacros returns the values of the macros available in shell commands
+func (fe *FileExplorer) shellMacros() shell.Macros {
+	_, currentName := fe.currentList.GetItemText(fe.currentList.GetCurrentItem())
+	currentPath := filepath.Join(fe.context.CurrentPath, currentName)
+	selection := fe.markedFiles
+	if len(selection) == 0 {
+		selection = []string{currentPath}
+	}
+	yanked := fe.yankedMarkedFiles
+	if len(yanked) == 0 && fe.yankedFile != "" {
+		yanked = []string{fe.yankedFile}
+	}
+	return shell.Macros{
+		File:      currentPath,
+		Dir:       fe.context.CurrentPath,
+		Selection: selection,
+		Yanked:    yanked,
+	}
+}
+
+// runShellCommand runs a command line with expanded macros in the current
+// directory. The flags -w, -p and -f in front of the command wait for a key
+// press, capture the output in a pager or detach the command.
+func (fe *FileExplorer) runShellCommand(commandLine string) error {
+	options, commandLine := shell.ParseFlags(commandLine)
+	if commandLine == "" {
+		return errors.New("missing command")
+	}
+	expandedLine := shell.Expand(commandLine, fe.shellMacros())
+	if options.Capture {
+		fe.runCapturedShellCommand(commandLine, expandedLine)
+		return nil
+	}
+	cmd := shell.Command(expandedLine, fe.context.CurrentPath)
+	defer fe.setCurrentDirectory(fe.context.CurrentPath)
+	if options.Detach {
+		return shell.RunDetached(cmd)
+	}
+	err := shell.RunSuspended(fe.context.App, cmd, options.Wait)
+	if options.Wait {
+		// The error was already shown before waiting for the key press
+		return nil
+	}
+	return err
+}
+
+// runCapturedShellCommand shows the output of a command in a pager while it
+// runs. Closing the pager stops the command; once it exits on its own, the
+// directory is reloaded to show the files it changed.
+func (fe *FileExplorer) runCapturedShellCommand(title string, commandLine string) {
+	commandContext, cancel := context.WithCancel(context.Background())
+	cmd := shell.CommandContext(commandContext, commandLine, fe.context.CurrentPath)
+	pager := fe.page("$ " + title)
+	closePager := pager.onClose
+	pager.onClose = func() {
+		cancel()
+		closePager()
+	}
+	pager.textView.SetChangedFunc(func() {
+		fe.context.App.Draw()
+	})
+	go func() {
+		writer := tview.ANSIWriter(pager.textView)
+		if err := shell.RunCaptured(cmd, writer); err != nil && commandContext.Err() == nil {
+			fmt.Fprintln(writer, "\n"+err.Error())
+		}
+		cancel()
+		fe.context.App.QueueUpdateDraw(func() {
+			// The pager was closed, which reloaded the directory, or the
+			// explorer was left for another mode
+			if fe.dialog == pager && fe.context.App.GetFocus() == pager.textView {
+				fe.setCurrentDirectory(fe.context.CurrentPath)
+			}
+		})
+	}()
+}
+
+// editFile opens a file, by default the current one, in the editor. A
+// position of the form <line>[:<column>] jumps to that position.
+func (fe *FileExplorer) editFile(file string, position string) error {
+	if position == "" && positionRegexp.MatchString(file) {
+		file, position = "", file
+	}
+	line, column := 0, 0
+	if position != "" {
+		match := positionRegexp.FindStringSubmatch(position)
+		if match == nil {
+			return fmt.Errorf("invalid position %q, expected <line>[:<column>]", position)
+		}
+		line, _ = strconv.Atoi(match[1])
+		column, _ = strconv.Atoi(match[2])
+	}
+	filePath := helper.GetAbsFilePath(file, fe.context.CurrentPath)
+	if file == "" {
+		_, currentName := fe.currentList.GetItemText(fe.currentList.GetCurrentItem())
+		filePath = filepath.Join(fe.context.CurrentPath, currentName)
+	}
+	return helper.OpenInEditor(filePath, line, column, fe.context.ChooseFilePath, fe.context.App, fe.context.Config)
+}
+
+// showMessage displays a message in the footer until the next input
+func (fe *FileExplorer) showMessage(message string) {
+	fe.footer = tview.NewInputField().SetText(message)
+}
+
 func (fe *FileExplorer) setLastDirectory() error {
 	// Write current path to a temporary file that can be sourced by shell
-	tempFile := os.Getenv("HOME") + "/.gofileyourself_lastdir"
+	tempFile := fe.context.Config.Paths.LastDir
+	if err := os.MkdirAll(filepath.Dir(tempFile), 0o700); err != nil {
+		return err
+	}
 	if err := os.WriteFile(tempFile, []byte(fe.context.CurrentPath), 0o644); err != nil {
 		return err
 	}
@@ -408,19 +699,36 @@ func (fe *FileExplorer) quitAndChangeDirectory() {
 	fe.context.App.Stop()
 }
 
+// deleteFile moves a file to the trash, unless trashing is disabled or the
+// delete is forced, in which case it is removed permanently
+func (fe *FileExplorer) deleteFile(path string, isForcedDelete bool) error {
+	if isForcedDelete {
+		return os.RemoveAll(path)
+	}
+	if fe.context.Config.Trash {
+		return trash.Trash(path)
+	}
+	return os.Remove(path)
+}
+
+// confirmDelete asks for confirmation as configured for the kind of delete
+func (fe *FileExplorer) confirmDelete(paths []string, isForcedDelete bool, onConfirm func()) {
+	title, mode := "Move to trash?", fe.context.Config.Confirm.Trash
+	if isForcedDelete || !fe.context.Config.Trash {
+		title, mode = "Delete permanently?", fe.context.Config.Confirm.Delete
+	}
+	fe.confirmForPaths(title, mode, paths, onConfirm)
+}
+
 func (fe *FileExplorer) deleteCurrentFile(isForcedDelete bool) {
 	_, currentName := fe.currentList.GetItemText(fe.currentList.GetCurrentItem())
 	currentPath := filepath.Join(fe.context.CurrentPath, currentName)
-	if isForcedDelete {
-		if err := os.RemoveAll(currentPath); err != nil {
+	fe.confirmDelete([]string{currentPath}, isForcedDelete, func() {
+		if err := fe.deleteFile(currentPath, isForcedDelete); err != nil {
 			return
 		}
-	} else {
-		if err := os.Remove(currentPath); err != nil {
-			return
-		}
-	}
-	fe.setCurrentDirectory(fe.context.CurrentPath)
+		fe.setCurrentDirectory(fe.context.CurrentPath)
+	})
 }
 
 func (fe *FileExplorer) yankCurrentFile() {
@@ -439,66 +747,131 @@ func (fe *FileExplorer) pasteYankedFile() {
 	fe.setCurrentDirectory(fe.context.CurrentPath)
 }
 
-func (fe *FileExplorer) renameMarkedFiles() {
-	tempFile, err := os.CreateTemp("", "gofileyourself_rm")
+func (fe *FileExplorer) renameMarkedFiles() error {
+	if len(fe.markedFiles) == 0 {
+		return errors.New("no marked files")
+	}
+	if err := os.MkdirAll(paths.RuntimeDir(), 0o700); err != nil {
+		return err
+	}
+	tempFile, err := os.CreateTemp(paths.RuntimeDir(), "mrename")
 	if err != nil {
-		return
+		return err
 	}
 	defer tempFile.Close()
 	defer os.Remove(tempFile.Name())
 
-	if len(fe.markedFiles) == 0 {
-		return
-	}
 	for _, file := range fe.markedFiles {
 		fmt.Fprintln(tempFile, filepath.Base(file))
 	}
-	helper.OpenInNvim(tempFile.Name(), fe.context.ChooseFilePath, fe.context.App, fe.context.Config.HistoryLen)
+	if err := editor.Resolve(fe.context.Config.Editor).Edit(fe.context.App, tempFile.Name(), 0, 0); err != nil {
+		return err
+	}
 
-	file, err := os.Open(tempFile.Name())
+	content, err := os.ReadFile(tempFile.Name())
 	if err != nil {
-		return
+		return err
 	}
-	fileReader := bufio.NewReader(file)
-	lineIdx := 0
-	for {
-		line, _, err := fileReader.ReadLine()
-		if len(line) > 0 {
-			if lineIdx <= len(fe.markedFiles) {
-				path := fe.markedFiles[lineIdx]
-				helper.RenameFile(path, string(filepath.Join(filepath.Dir(path), string(line))))
-			}
-			lineIdx++
-		}
-		if err != nil {
-			break
+	lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	plan, err := rename.NewPlan(fe.markedFiles, lines)
+	if err != nil {
+		return err
+	}
+	fe.confirmRenamePlan(plan)
+	return nil
+}
+
+// renameSources returns the marked files or, without marks, the current file
+func (fe *FileExplorer) renameSources() []string {
+	if len(fe.markedFiles) > 0 {
+		return fe.markedFiles
+	}
+	_, currentName := fe.currentList.GetItemText(fe.currentList.GetCurrentItem())
+	return []string{filepath.Join(fe.context.CurrentPath, currentName)}
+}
+
+// planPatternRename computes the rename plan of a pattern for the rename sources
+func (fe *FileExplorer) planPatternRename(expression string) ([]string, *rename.Plan, error) {
+	sources := fe.renameSources()
+	pattern, err := rename.ParsePattern(expression)
+	if err != nil {
+		return nil, nil, err
+	}
+	targets, err := pattern.Targets(sources)
+	if err != nil {
+		return nil, nil, err
+	}
+	plan, err := rename.NewPlan(sources, targets)
+	return targets, plan, err
+}
+
+// renamePatternPreview lists old and new names while a pattern is typed
+func (fe *FileExplorer) renamePatternPreview(expression string) *tview.TextView {
+	explorerTheme := theme.Current()
+	textView := tview.NewTextView().SetDynamicColors(true).SetWrap(false)
+	textView.SetTextColor(explorerTheme.TextStrong).SetBackgroundColor(explorerTheme.Background)
+
+	text := ""
+	targets, _, err := fe.planPatternRename(expression)
+	if err != nil {
+		text += theme.Tag(explorerTheme.Error, "b") + tview.Escape(err.Error()) + "[-::-]\n\n"
+	}
+	for i, source := range fe.renameSources() {
+		name := tview.Escape(filepath.Base(source))
+		if i >= len(targets) {
+			text += name + "\n"
+		} else if targets[i] == filepath.Base(source) {
+			text += theme.Tag(explorerTheme.Muted, "") + name + "[-::]\n"
+		} else {
+			text += name + " " + theme.Tag(explorerTheme.Muted, "") + "->[-::] " + theme.Tag(explorerTheme.Success, "") + tview.Escape(targets[i]) + "[-::]\n"
 		}
 	}
+	textView.SetText(text)
+	return textView
+}
+
+func (fe *FileExplorer) renameByPattern(expression string) error {
+	_, plan, err := fe.planPatternRename(expression)
+	if err != nil {
+		return err
+	}
+	defer fe.setCurrentDirectory(fe.context.CurrentPath)
+	// The marks are kept to retry a failed rename
+	if err := plan.Apply(); err != nil {
+		return err
+	}
 	fe.markedFiles = []string{}
-	fe.setCurrentDirectory(fe.context.CurrentPath)
+	return nil
 }
 
-func (fe *FileExplorer) deleteMarkedFiles(isForcedDelete bool) {
-	filesToRemove := []string{}
-	for _, file := range fe.markedFiles {
-		if isForcedDelete {
-			if err := os.RemoveAll(file); err != nil {
-				return
-			} else {
-				filesToRemove = append(filesToRemove, file)
-			}
+// confirmRenamePlan shows the diff of a rename plan and applies it once accepted
+func (fe *FileExplorer) confirmRenamePlan(plan *rename.Plan) {
+	title := fmt.Sprintf("Rename %d files?", len(plan.Renames))
+	fe.confirm(title, plan.Preview(fe.context.CurrentPath), func() {
+		// The marks are kept to retry a failed rename
+		if err := plan.Apply(); err != nil {
+			fe.showMessage("rename: " + err.Error())
 		} else {
-			if err := os.Remove(file); err != nil {
-				return
-			} else {
-				filesToRemove = append(filesToRemove, file)
+			fe.markedFiles = []string{}
+		}
+		fe.setCurrentDirectory(fe.context.CurrentPath)
+	})
+}
+
+func (fe *FileExplorer) deleteMarkedFiles(isForcedDelete bool) {
+	fe.confirmDelete(fe.markedFiles, isForcedDelete, func() {
+		filesToRemove := []string{}
+		for _, file := range fe.markedFiles {
+			if err := fe.deleteFile(file, isForcedDelete); err != nil {
+				break
 			}
+			filesToRemove = append(filesToRemove, file)
 		}
-	}
-	for _, file := range filesToRemove {
-		fe.markedFiles = helper.DeleteItem(fe.markedFiles, file)
-	}
-	fe.setCurrentDirectory(fe.context.CurrentPath)
+		for _, file := range filesToRemove {
+			fe.markedFiles = helper.DeleteItem(fe.markedFiles, file)
+		}
+		fe.setCurrentDirectory(fe.context.CurrentPath)
+	})
 }
 
 func (fe *FileExplorer) toggleMarkForCurrentFile() {
@@ -533,15 +906,11 @@ func (fe *FileExplorer) pasteMarkedFiles() {
 func (fe *FileExplorer) setAnchor(key string) {
 	_, currentName := fe.currentList.GetItemText(fe.currentList.GetCurrentItem())
 	anchor := key + " > " + fe.context.CurrentPath + "/" + currentName
-	homeDir, _ := os.UserHomeDir()
-	anchorFilePath := homeDir + "/.gofileyourself_anchors"
-	helper.AppendOrReplaceLineInFile(anchorFilePath, anchor)
+	helper.AppendOrReplaceLineInFile(fe.context.Config.Paths.Anchors, anchor)
 }
 
 func (fe *FileExplorer) jumpToAnchor(key string) {
-	homeDir, _ := os.UserHomeDir()
-	anchor := homeDir + "/.gofileyourself_anchors"
-	anchor, err := helper.GetLineWithKey(anchor, key)
+	anchor, err := helper.GetLineWithKey(fe.context.Config.Paths.Anchors, key)
 	if err != nil {
 		return
 	}
@@ -562,7 +931,7 @@ func (fe *FileExplorer) cycleRecent(isBackward bool) {
 	if fe.cycleRecentPosition < 0 {
 		fe.cycleRecentPosition = 0
 	}
-	recentFile, err := helper.GetRecentFile(fe.cycleRecentPosition, fe.context.Config.HistoryLen)
+	recentFile, err := helper.GetRecentFile(fe.cycleRecentPosition, fe.context.Config)
 	if err != nil {
 		if isBackward {
 			fe.cycleRecentPosition = 0
@@ -579,193 +948,12 @@ func (fe *FileExplorer) cycleRecent(isBackward bool) {
 func (fe *FileExplorer) SetupKeyBindings() {
 	fe.currentList.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
 		defer fe.Draw()
-		switch event.Key() {
-		case tcell.KeyCtrlH:
-			fe.context.ShowHiddenFiles = !fe.context.ShowHiddenFiles
-
-			// Remember current selection before refresh
-			_, currentName := fe.currentList.GetItemText(fe.currentList.GetCurrentItem())
-
-			// Remember selected directory name if we're showing a directory
-			var selectedName string
-			if list, ok := fe.selectedList.(*tview.List); ok {
-				_, selectedName = list.GetItemText(list.GetCurrentItem())
-			}
-
-			// Refresh the view
-			if err := fe.setCurrentDirectory(fe.context.CurrentPath); err != nil {
-				return event
-			}
-
-			// Restore current selection
-			if idx := helper.FindExactItem(fe.currentList, currentName); idx >= 0 {
-				fe.setCurrentLine(idx)
-			}
-
-			// Restore selected directory selection if applicable
-			if list, ok := fe.selectedList.(*tview.List); ok {
-				if idx := helper.FindExactItem(list, selectedName); idx >= 0 {
-					list.SetCurrentItem(idx)
-					absoluteSelectedPath, _ := filepath.Abs(filepath.Join(fe.context.CurrentPath, currentName))
-					fe.directoryToIndexMap[absoluteSelectedPath] = idx
-				}
-			}
-			return nil
-		case tcell.KeyCtrlD: // scroll 10 down
-			scrollAmount := fe.currentList.GetItemCount() / 2
-			if scrollAmount > MAX_SCROLL_AMOUNT {
-				scrollAmount = MAX_SCROLL_AMOUNT
-			}
-			fe.setCurrentLine(fe.currentList.GetCurrentItem() + scrollAmount)
-			return nil
-		case tcell.KeyCtrlU: // scroll 10 up
-			scrollAmount := fe.currentList.GetItemCount() / 2
-			if scrollAmount > MAX_SCROLL_AMOUNT {
-				scrollAmount = MAX_SCROLL_AMOUNT
-			}
-			fe.setCurrentLine(fe.currentList.GetCurrentItem() - scrollAmount)
-			return nil
-		}
-		rune := event.Rune()
-		fe.keyBuffer += string(rune)
-		if len(fe.keyBuffer) > 5 {
-			fe.keyBuffer = fe.keyBuffer[4:]
-		}
-		if strings.HasSuffix(fe.keyBuffer, "gg") {
-			fe.keyBuffer = ""
-			fe.setCurrentLine(0)
-			return nil
-		} else if strings.HasSuffix(fe.keyBuffer, "dd") {
-			fe.keyBuffer = ""
-			fe.deleteCurrentFile(false)
-			return nil
-		} else if strings.HasSuffix(fe.keyBuffer, "DD") {
-			fe.keyBuffer = ""
-			fe.deleteCurrentFile(true)
-			return nil
-		} else if strings.HasSuffix(fe.keyBuffer, "yy") {
-			fe.keyBuffer = ""
-			fe.yankCurrentFile()
-			return nil
-		} else if strings.HasSuffix(fe.keyBuffer, "pp") {
-			fe.keyBuffer = ""
-			fe.pasteYankedFile()
-			return nil
-		} else if strings.HasSuffix(fe.keyBuffer, "mm") {
-			fe.keyBuffer = ""
-			fe.toggleMarkForCurrentFile()
-			return nil
-		} else if strings.HasSuffix(fe.keyBuffer, "mu") {
-			fe.keyBuffer = ""
-			fe.unmarkAllFiles()
-			return nil
-		} else if strings.HasSuffix(fe.keyBuffer, "md") {
+		if event.Key() == tcell.KeyEscape {
 			fe.keyBuffer = ""
-			fe.deleteMarkedFiles(false)
-			return nil
-		} else if strings.HasSuffix(fe.keyBuffer, "mD") {
-			fe.keyBuffer = ""
-			fe.deleteMarkedFiles(true)
-			return nil
-		} else if strings.HasSuffix(fe.keyBuffer, "my") {
-			fe.keyBuffer = ""
-			fe.yankMarkedFiles()
-			return nil
-		} else if strings.HasSuffix(fe.keyBuffer, "mp") {
-			fe.keyBuffer = ""
-			fe.pasteMarkedFiles()
-			return nil
-		} else if match := regexp.MustCompile(`A([a-zA-Z0-9]+)$`).FindStringSubmatch(fe.keyBuffer); match != nil {
-			key := match[1]
-			fe.keyBuffer = ""
-			fe.setAnchor(key)
-			return nil
-		} else if match := regexp.MustCompile(`a([a-zA-Z0-9]+)$`).FindStringSubmatch(fe.keyBuffer); match != nil {
-			key := match[1]
-			fe.keyBuffer = ""
-			fe.jumpToAnchor(key)
-			return nil
-		}
-		switch rune {
-		case 'r':
-			fe.cycleRecent(false)
-			return nil
-		case 'R':
-			fe.cycleRecent(true)
-			return nil
-		case 'M':
-			fe.toggleMarkForCurrentFile()
-			return nil
-		case 'G':
-			fe.setCurrentLine(fe.currentList.GetItemCount() - 1)
-			return nil
-		case 'S':
-			fe.quitAndChangeDirectory()
-			return nil
-		case 'j': // scroll down
-			fe.setCurrentLine(fe.currentList.GetCurrentItem() + 1)
-			return nil
-		case 'k': // scroll up
-			fe.setCurrentLine(fe.currentList.GetCurrentItem() - 1)
-			return nil
-		case 'q': // quit
-			fe.context.App.Stop()
-			return nil
-		case 'l': // open dir or file
-			currentItem := fe.currentList.GetCurrentItem()
-			_, fileName := fe.currentList.GetItemText(currentItem)
-			filePath := filepath.Join(fe.context.CurrentPath, fileName)
-			fileInfo, err := os.Stat(filePath)
-			if err != nil {
-				return event
-			}
-			if fileInfo.IsDir() {
-				if err := fe.setCurrentDirectory(filePath); err != nil {
-					return event
-				}
-			} else {
-				helper.OpenInNvim(filePath, fe.context.ChooseFilePath, fe.context.App, fe.context.Config.HistoryLen)
-				return nil
-			}
-			return nil
-		case 'h': // go up directory
-			dirPath := filepath.Join(fe.context.CurrentPath, "..")
-			if err := fe.setCurrentDirectory(dirPath); err != nil {
-				return event
-			}
-			return nil
-		case '/': // search
-			fe.handleFooterInput("/")
-			return nil
-		case ':': // command
-			fe.handleFooterInput(":")
-			return nil
-		case 'n': // cycle search
-			if len(fe.currentSearchIndeces) > 0 {
-				currentIndex := fe.currentList.GetCurrentItem()
-				for _, index := range fe.currentSearchIndeces {
-					if index > currentIndex {
-						fe.setCurrentLine(index)
-						return nil
-					}
-				}
-				fe.setCurrentLine(fe.currentSearchIndeces[0])
-			}
-		case 'N': // cycle search backwards
-			if len(fe.currentSearchIndeces) > 0 {
-				currentIndex := fe.currentList.GetCurrentItem()
-				for i := len(fe.currentSearchIndeces) - 1; i >= 0; i-- {
-					if fe.currentSearchIndeces[i] < currentIndex {
-						fe.setCurrentLine(fe.currentSearchIndeces[i])
-						return nil
-					}
-				}
-				// If no smaller index found, wrap around to the last item
-				fe.setCurrentLine(fe.currentSearchIndeces[len(fe.currentSearchIndeces)-1])
-			}
-
+			fe.searchInput = ""
 			return nil
 		}
+		fe.handleKeymapKey(keyName(event), event.Key() == tcell.KeyRune, event.Rune())
 		return nil
 	})
 }
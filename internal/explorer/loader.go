@@ -0,0 +1,144 @@
+package explorer
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+
+	"github.com/thilobro/gofileyourself/internal/config"
+	"github.com/thilobro/gofileyourself/internal/helper"
+	"github.com/thilobro/gofileyourself/internal/picture"
+	"github.com/thilobro/gofileyourself/internal/preview"
+	"github.com/thilobro/gofileyourself/internal/theme"
+
+	"github.com/rivo/tview"
+)
+
+// PREVIEW_CACHE_SIZE is the number of previews kept by the loader
+const PREVIEW_CACHE_SIZE = 64
+
+// previewData is the content of the preview pane, generated by the loader
+type previewData struct {
+	isDirectory bool
+	// entries are listed without marks, which change without the directory
+	entries []helper.Entry
+	chunk   *helper.FileChunk
+	isTail  bool
+	// isHex is set for the hex dump of files that are not text
+	isHex   bool
+	picture *picture.Picture
+}
+
+// previewLoader is shared by all explorers, so that the cache outlives the
+// explorer of a mode and a new explorer cancels the requests of the old one
+var previewLoader *preview.Loader[previewData]
+
+// loadPreviewData lists a directory or an archive, decodes a picture or reads
+// the first or, for tail files, the last chunk of a file, as a hex dump unless
+// it is text. It runs on the goroutine of the loader, so it styles the
+// preview with previewTheme instead of the active theme.
+func loadPreviewData(loadContext context.Context, path string, listOptions helper.ListOptions, options config.PreviewConfig, previewTheme *theme.Theme) (previewData, error) {
+	entries, err := helper.ListEntries(path, listOptions, false, nil)
+	if err != nil {
+		return previewData{}, err
+	}
+	if entries != nil {
+		return previewData{isDirectory: true, entries: entries}, nil
+	}
+	if err := loadContext.Err(); err != nil {
+		return previewData{}, err
+	}
+	isTail := isTailFile(path, options.Tail)
+	chunk, err := helper.LoadFileChunk(path, 0, isTail, options, previewTheme)
+	if err != nil || chunk != nil {
+		return previewData{chunk: chunk, isTail: isTail}, err
+	}
+	if options.ImageProtocol != config.ImageNone {
+		decoded, err := picture.Decode(path)
+		if err != nil || decoded != nil {
+			return previewData{picture: decoded}, err
+		}
+	}
+	chunk, err = helper.LoadArchiveChunk(loadContext, path, options, previewTheme)
+	if err != nil || chunk != nil {
+		return previewData{chunk: chunk}, err
+	}
+	chunk, err = helper.LoadHexChunk(path, isTail, options, previewTheme)
+	return previewData{chunk: chunk, isTail: isTail, isHex: true}, err
+}
+
+// loadSelected shows a placeholder in the preview pane and requests the
+// preview of a path, which replaces it once it is generated
+func (fe *FileExplorer) loadSelected(path string) {
+	if previewLoader == nil {
+		app := fe.context.App
+		previewLoader = preview.NewLoader[previewData](PREVIEW_CACHE_SIZE, func(update func()) {
+			app.QueueUpdateDraw(update)
+		})
+	}
+	placeholder := tview.NewTextView().SetDynamicColors(true)
+	placeholder.SetText(theme.Tag(theme.Current().Muted, "") + "Loading...[-::]")
+	fe.selectedList = placeholder
+
+	listOptions := helper.NewListOptions(fe.context.Config)
+	previewOptions := fe.context.Config.Preview
+	previewTheme := theme.Current()
+	load := func(loadContext context.Context, path string) (previewData, error) {
+		return loadPreviewData(loadContext, path, listOptions, previewOptions, previewTheme)
+	}
+	previewLoader.Load(path, load, func(data previewData, err error) {
+		// Something else, e.g. the rename preview, took the place
+		if fe.selectedList != placeholder {
+			return
+		}
+		if err != nil {
+			errorView := tview.NewTextView().SetDynamicColors(true)
+			errorView.SetText(theme.Tag(theme.Current().Error, "") + tview.Escape(err.Error()) + "[-::]")
+			fe.replaceSelected(placeholder, errorView)
+			return
+		}
+		fe.replaceSelected(placeholder, fe.newSelected(path, data))
+	})
+}
+
+// newSelected creates the preview pane for generated preview data
+func (fe *FileExplorer) newSelected(path string, data previewData) tview.Primitive {
+	selectedName := fe.pendingSelectedName
+	fe.pendingSelectedName = ""
+	if data.isDirectory && len(data.entries) == 0 {
+		return tview.NewTextArea().SetText("Directory is empty", false)
+	}
+	if data.isDirectory {
+		entries := slices.Clone(data.entries)
+		for i := range entries {
+			entries[i].Marked = slices.Contains(fe.markedFiles, filepath.Join(path, entries[i].Name))
+		}
+		list := helper.NewEntryList(entries)
+		list.SetCurrentItem(fe.directoryToIndexMap[path])
+		if index := helper.FindExactItem(list, selectedName); selectedName != "" && index >= 0 {
+			list.SetCurrentItem(index)
+			fe.directoryToIndexMap[path] = index
+		}
+		return list
+	}
+	if data.picture != nil {
+		return picture.NewView(data.picture, fe.context.Config.Preview.ImageProtocol)
+	}
+	filePreview := newFilePreview(path, fe.context.Config.Preview, data.chunk, data.isTail, data.isHex)
+	if filePreview.isTail {
+		filePreview.textView.ScrollToEnd()
+	} else {
+		filePreview.scrollTo(fe.previewOffsets[path])
+	}
+	fe.preview = filePreview
+	return filePreview.textView
+}
+
+// replaceSelected swaps the preview pane in place. Unlike Draw it does not
+// touch the root of the application, which may belong to another mode by now.
+func (fe *FileExplorer) replaceSelected(old tview.Primitive, new tview.Primitive) {
+	fe.selectedList = new
+	fe.listFlex.RemoveItem(old)
+	fe.listFlex.AddItem(new, 0, 3, false)
+	fe.applyTheme()
+}
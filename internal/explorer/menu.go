@@ -0,0 +1,83 @@
+package explorer
+
+import (
+	"github.com/thilobro/gofileyourself/internal/theme"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// menuDialog lets the user pick one of several entries
+type menuDialog struct {
+	root     tview.Primitive
+	list     *tview.List
+	onSelect func(index int)
+	onCancel func()
+}
+
+func newMenuDialog(title string, entries []string, onSelect func(index int), onCancel func()) *menuDialog {
+	explorerTheme := theme.Current()
+	list := tview.NewList().ShowSecondaryText(false)
+	for _, entry := range entries {
+		list.AddItem(tview.Escape(entry), "", 0, nil)
+	}
+	list.SetBorder(true).
+		SetTitle(" " + title + " ").
+		SetBorderColor(explorerTheme.Accent).
+		SetTitleColor(explorerTheme.Accent).
+		SetBackgroundColor(explorerTheme.Panel)
+	list.SetMainTextColor(explorerTheme.Text).
+		SetSelectedStyle(theme.SelectedStyle(explorerTheme.Selection))
+
+	return &menuDialog{
+		root:     centerDialog(list, len(entries)+2),
+		list:     list,
+		onSelect: onSelect,
+		onCancel: onCancel,
+	}
+}
+
+func (dialog *menuDialog) Root() tview.Primitive {
+	return dialog.root
+}
+
+func (dialog *menuDialog) Focus() tview.Primitive {
+	return dialog.list
+}
+
+func (dialog *menuDialog) InputCapture(event *tcell.EventKey) *tcell.EventKey {
+	switch event.Key() {
+	case tcell.KeyEnter:
+		dialog.onSelect(dialog.list.GetCurrentItem())
+		return nil
+	case tcell.KeyEscape:
+		dialog.onCancel()
+		return nil
+	case tcell.KeyUp, tcell.KeyDown:
+		return event
+	}
+	switch event.Rune() {
+	case 'j':
+		dialog.list.SetCurrentItem((dialog.list.GetCurrentItem() + 1) % dialog.list.GetItemCount())
+	case 'k':
+		dialog.list.SetCurrentItem(dialog.list.GetCurrentItem() - 1)
+	case 'l':
+		dialog.onSelect(dialog.list.GetCurrentItem())
+	case 'q', 'h':
+		dialog.onCancel()
+	}
+	return nil
+}
+
+// choose shows a menu and runs onSelect with the index of the picked entry
+func (fe *FileExplorer) choose(title string, entries []string, onSelect func(index int)) {
+	fe.dialog = newMenuDialog(title, entries, func(index int) {
+		fe.dialog = nil
+		onSelect(index)
+		fe.Draw()
+	}, func() {
+		fe.dialog = nil
+		fe.Draw()
+	})
+	fe.Draw()
+}
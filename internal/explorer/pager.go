@@ -0,0 +1,86 @@
+package explorer
+
+import (
+	"github.com/thilobro/gofileyourself/internal/theme"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// pagerDialog shows a scrollable text, e.g. the output of a shell command
+type pagerDialog struct {
+	root     tview.Primitive
+	textView *tview.TextView
+	onClose  func()
+}
+
+func newPagerDialog(title string, onClose func()) *pagerDialog {
+	explorerTheme := theme.Current()
+	textView := tview.NewTextView().
+		SetDynamicColors(true).
+		SetScrollable(true).
+		SetWrap(false)
+	textView.SetBorder(true).
+		SetTitle(" " + tview.Escape(title) + " ").
+		SetBorderColor(explorerTheme.Accent).
+		SetTitleColor(explorerTheme.Accent).
+		SetBackgroundColor(explorerTheme.Panel)
+	textView.SetTextColor(explorerTheme.TextStrong)
+
+	return &pagerDialog{
+		root:     centerDialog(textView, MAX_DIALOG_HEIGHT+1),
+		textView: textView,
+		onClose:  onClose,
+	}
+}
+
+func (dialog *pagerDialog) Root() tview.Primitive {
+	return dialog.root
+}
+
+func (dialog *pagerDialog) Focus() tview.Primitive {
+	return dialog.textView
+}
+
+func (dialog *pagerDialog) InputCapture(event *tcell.EventKey) *tcell.EventKey {
+	row, _ := dialog.textView.GetScrollOffset()
+	_, _, _, height := dialog.textView.GetInnerRect()
+	switch event.Key() {
+	case tcell.KeyEscape, tcell.KeyEnter:
+		dialog.onClose()
+		return nil
+	case tcell.KeyCtrlD:
+		dialog.textView.ScrollTo(row+height/2, 0)
+		return nil
+	case tcell.KeyCtrlU:
+		dialog.textView.ScrollTo(max(row-height/2, 0), 0)
+		return nil
+	case tcell.KeyUp, tcell.KeyDown, tcell.KeyPgUp, tcell.KeyPgDn:
+		return event
+	}
+	switch event.Rune() {
+	case 'j':
+		dialog.textView.ScrollTo(row+1, 0)
+	case 'k':
+		dialog.textView.ScrollTo(max(row-1, 0), 0)
+	case 'g':
+		dialog.textView.ScrollToBeginning()
+	case 'G':
+		dialog.textView.ScrollToEnd()
+	case 'q':
+		dialog.onClose()
+	}
+	return nil
+}
+
+// page opens a pager dialog and returns it so that text can be written to it
+func (fe *FileExplorer) page(title string) *pagerDialog {
+	pager := newPagerDialog(title, func() {
+		fe.dialog = nil
+		fe.setCurrentDirectory(fe.context.CurrentPath)
+		fe.Draw()
+	})
+	fe.dialog = pager
+	fe.Draw()
+	return pager
+}
@@ -0,0 +1,420 @@
+package explorer
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/thilobro/gofileyourself/internal/config"
+	"github.com/thilobro/gofileyourself/internal/formatter"
+	"github.com/thilobro/gofileyourself/internal/helper"
+	"github.com/thilobro/gofileyourself/internal/theme"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// filePreview shows the contents of the selected file. It can be scrolled
+// from the list or focused to scroll and search in it. Large files are read
+// in chunks while scrolling down; in tail mode only their end is shown.
+// Archives are shown as a list of their entries, other files that are not
+// text as a hex dump.
+type filePreview struct {
+	textView *tview.TextView
+	path     string
+	options  config.PreviewConfig
+	lines    []formatter.Line
+	isHex    bool
+	// start and end are the byte offsets of the loaded lines, size is the
+	// size of the file
+	start       int64
+	end         int64
+	size        int64
+	isTail      bool
+	lineNumbers bool
+	search      string
+	// matchLines holds the line of every search match, match the index of
+	// the highlighted one
+	matchLines []int
+	match      int
+}
+
+// newFilePreview shows a chunk of a file, lines of a hex dump with isHex
+func newFilePreview(path string, options config.PreviewConfig, chunk *helper.FileChunk, isTail bool, isHex bool) *filePreview {
+	textView := tview.NewTextView().
+		SetDynamicColors(true).
+		SetRegions(true).
+		SetWrap(options.Wrap && !isHex).
+		SetWordWrap(true)
+	preview := &filePreview{
+		textView:    textView,
+		path:        path,
+		options:     options,
+		isHex:       isHex,
+		lineNumbers: options.LineNumbers,
+	}
+	preview.setChunk(chunk, isTail)
+	return preview
+}
+
+// isTailFile reports whether the name of a file matches one of the patterns
+// of files that are previewed from their end
+func isTailFile(path string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if isMatch, _ := filepath.Match(pattern, filepath.Base(path)); isMatch {
+			return true
+		}
+	}
+	return false
+}
+
+// load reads the first chunk of the file, or the last one in tail mode
+func (preview *filePreview) load(isTail bool) error {
+	var chunk *helper.FileChunk
+	var err error
+	if preview.isHex {
+		chunk, err = helper.LoadHexChunk(preview.path, isTail, preview.options, theme.Current())
+	} else {
+		chunk, err = helper.LoadFileChunk(preview.path, 0, isTail, preview.options, theme.Current())
+	}
+	if err != nil || chunk == nil {
+		return err
+	}
+	preview.setChunk(chunk, isTail)
+	return nil
+}
+
+// setChunk replaces the lines with the ones of a chunk
+func (preview *filePreview) setChunk(chunk *helper.FileChunk, isTail bool) {
+	preview.isTail = isTail
+	// Chunks are cached, more lines must not be appended to their array
+	preview.lines = slices.Clip(chunk.Lines)
+	preview.start, preview.end, preview.size = chunk.Start, chunk.End, chunk.Size
+	preview.render()
+}
+
+// loadMore appends the next chunk of the file to the lines
+func (preview *filePreview) loadMore() error {
+	if !preview.hasMore() {
+		return nil
+	}
+	chunk, err := helper.LoadFileChunk(preview.path, preview.end, false, preview.options, theme.Current())
+	if err != nil || chunk == nil {
+		return err
+	}
+	preview.lines = append(preview.lines, chunk.Lines...)
+	preview.end, preview.size = chunk.End, chunk.Size
+	preview.render()
+	return nil
+}
+
+// hasMore reports whether lines below the loaded ones can be loaded. Hex
+// dumps stop at hex_max_bytes.
+func (preview *filePreview) hasMore() bool {
+	return !preview.isHex && !preview.isTail && preview.end < preview.size
+}
+
+// toggleHex switches between the text and the hex dump of a text file.
+// Binary files and the entries of archives stay as they are.
+func (preview *filePreview) toggleHex() {
+	if !helper.IsTextFile(preview.path) {
+		return
+	}
+	preview.isHex = !preview.isHex
+	preview.textView.SetWrap(preview.options.Wrap && !preview.isHex)
+	preview.load(false)
+	preview.textView.ScrollToBeginning()
+}
+
+// render sets the text of the lines with line numbers and search matches.
+// Every match is a region, so that it can be highlighted and scrolled to.
+func (preview *filePreview) render() {
+	var searchRegexp *regexp.Regexp
+	if preview.search != "" {
+		// Smart case: the search ignores case unless it contains capitals
+		expression := regexp.QuoteMeta(preview.search)
+		if !strings.ContainsFunc(preview.search, unicode.IsUpper) {
+			expression = "(?i)" + expression
+		}
+		searchRegexp = regexp.MustCompile(expression)
+	}
+	mutedTag := theme.Tag(theme.Current().Muted, "")
+	numberWidth := len(strconv.Itoa(len(preview.lines)))
+	preview.matchLines = []int{}
+	var text strings.Builder
+	// The line numbers of a tail are unknown without reading all lines
+	// before it, hex dumps show offsets instead
+	isCut := preview.isTail && preview.start > 0
+	if isCut {
+		text.WriteString(mutedTag + "… " + helper.FormatSize(preview.start) + " before, g shows the start[-:-:-]\n")
+	}
+	for i, line := range preview.lines {
+		if preview.lineNumbers && !isCut && !preview.isHex {
+			text.WriteString(mutedTag + fmt.Sprintf("%*d ", numberWidth, i+1) + "[-:-:-]")
+		}
+		var matches [][]int
+		if searchRegexp != nil {
+			matches = searchRegexp.FindAllStringIndex(line.Plain(), -1)
+		}
+		if len(matches) == 0 {
+			text.WriteString(line.String())
+		} else {
+			text.WriteString(preview.highlightLine(line, i, matches))
+		}
+		text.WriteString("[-:-:-]\n")
+	}
+	if preview.hasMore() {
+		text.WriteString(mutedTag + "… " + helper.FormatSize(preview.size-preview.end) + " more, scroll down to load[-:-:-]\n")
+	} else if !preview.isTail && preview.end < preview.size {
+		text.WriteString(mutedTag + "… " + helper.FormatSize(preview.size-preview.end) + " more not shown, G shows the end[-:-:-]\n")
+	}
+	preview.textView.SetText(text.String())
+}
+
+// highlightLine returns the line with the byte ranges of matches highlighted
+// and counts the matches. A match may span several segments.
+func (preview *filePreview) highlightLine(line formatter.Line, lineIndex int, matches [][]int) string {
+	matchTag := theme.Tag(theme.Current().SearchHighlight, "b")
+	var text strings.Builder
+	offset := 0
+	for _, segment := range line {
+		tag := segment.Tag
+		if tag == "" {
+			tag = "[-:-:-]"
+		}
+		text.WriteString(tag)
+		for position := 0; position < len(segment.Text); {
+			start := offset + position
+			end := offset + len(segment.Text)
+			isMatch := false
+			for _, match := range matches {
+				if match[0] <= start && start < match[1] {
+					isMatch = true
+					end = min(end, match[1])
+					if match[0] == start {
+						text.WriteString(`["` + previewMatchRegion(len(preview.matchLines)) + `"]`)
+						preview.matchLines = append(preview.matchLines, lineIndex)
+					}
+					break
+				}
+				if match[0] > start {
+					end = min(end, match[0])
+					break
+				}
+			}
+			piece := tview.Escape(segment.Text[position : end-offset])
+			if isMatch {
+				text.WriteString(matchTag + piece + tag)
+				if isMatchEnd(matches, end) {
+					text.WriteString(`[""]`)
+				}
+			} else {
+				text.WriteString(piece)
+			}
+			position = end - offset
+		}
+		offset += len(segment.Text)
+	}
+	return text.String()
+}
+
+func isMatchEnd(matches [][]int, position int) bool {
+	for _, match := range matches {
+		if match[1] == position {
+			return true
+		}
+	}
+	return false
+}
+
+func previewMatchRegion(index int) string {
+	return "match" + strconv.Itoa(index)
+}
+
+// scroll moves the text by a number of rows, up for negative ones
+func (preview *filePreview) scroll(rows int) {
+	row, _ := preview.textView.GetScrollOffset()
+	preview.scrollTo(max(row+rows, 0))
+}
+
+// scrollTo scrolls to a row and loads more lines when it comes close to the
+// end of the loaded ones
+func (preview *filePreview) scrollTo(row int) {
+	preview.textView.ScrollTo(row, 0)
+	_, _, _, height := preview.textView.GetInnerRect()
+	for preview.hasMore() && row+2*max(height, MAX_SCROLL_AMOUNT) >= len(preview.lines) {
+		if err := preview.loadMore(); err != nil {
+			return
+		}
+	}
+}
+
+// scrollToBeginning scrolls to the start of the file, leaving tail mode if
+// the start is not loaded
+func (preview *filePreview) scrollToBeginning() {
+	if preview.isTail && preview.start > 0 {
+		preview.load(false)
+	}
+	preview.textView.ScrollToBeginning()
+}
+
+// scrollToEnd scrolls to the end of the file. Unless all lines are loaded,
+// it switches to tail mode, which reloads the end of growing files.
+func (preview *filePreview) scrollToEnd() {
+	if preview.end < preview.size || preview.isTail {
+		preview.load(true)
+	}
+	preview.textView.ScrollToEnd()
+}
+
+// toggleTail switches between the start and the end of the file
+func (preview *filePreview) toggleTail() {
+	if preview.isTail {
+		preview.load(false)
+		preview.textView.ScrollToBeginning()
+	} else {
+		preview.load(true)
+		preview.textView.ScrollToEnd()
+	}
+}
+
+// pageHeight returns the number of rows scrolled by half a page
+func (preview *filePreview) pageHeight() int {
+	_, _, _, height := preview.textView.GetInnerRect()
+	return min(max(height/2, 1), MAX_SCROLL_AMOUNT)
+}
+
+// setSearch highlights the occurrences of a search term and scrolls to the
+// first one at or below the top of the preview
+func (preview *filePreview) setSearch(search string) {
+	preview.search = search
+	preview.render()
+	row, _ := preview.textView.GetScrollOffset()
+	preview.match = 0
+	for i, line := range preview.matchLines {
+		if line >= row {
+			preview.match = i
+			break
+		}
+	}
+	preview.showMatch()
+}
+
+// nextMatch scrolls to the next or, backwards, the previous search match
+func (preview *filePreview) nextMatch(isBackward bool) {
+	if len(preview.matchLines) == 0 {
+		return
+	}
+	step := 1
+	if isBackward {
+		step = len(preview.matchLines) - 1
+	}
+	preview.match = (preview.match + step) % len(preview.matchLines)
+	preview.showMatch()
+}
+
+// showMatch highlights the current search match and scrolls to it
+func (preview *filePreview) showMatch() {
+	if len(preview.matchLines) == 0 {
+		preview.textView.Highlight()
+		return
+	}
+	preview.textView.Highlight(previewMatchRegion(preview.match)).ScrollToHighlight()
+}
+
+// setLineNumbers shows or hides the line numbers
+func (preview *filePreview) setLineNumbers(lineNumbers bool) {
+	preview.lineNumbers = lineNumbers
+	preview.render()
+	preview.showMatch()
+}
+
+// focusPreview moves the focus into the file preview, if a file is previewed
+func (fe *FileExplorer) focusPreview() {
+	if fe.preview == nil || fe.selectedList != fe.preview.textView {
+		return
+	}
+	fe.isPreviewFocused = true
+	fe.currentFocusedWidget = fe.preview.textView
+}
+
+// unfocusPreview moves the focus back to the current list
+func (fe *FileExplorer) unfocusPreview() {
+	fe.isPreviewFocused = false
+	fe.currentFocusedWidget = fe.currentList
+}
+
+// togglePreviewOption switches a boolean preview option for all files
+func (fe *FileExplorer) togglePreviewOption(key string) bool {
+	value, _ := fe.context.Config.Get(key)
+	isEnabled := value != "true"
+	if err := fe.setConfigValue(key, strconv.FormatBool(isEnabled)); err != nil {
+		fe.showMessage(err.Error())
+	}
+	return isEnabled
+}
+
+// previewInputCapture handles the keys while the preview is focused
+func (fe *FileExplorer) previewInputCapture(event *tcell.EventKey) *tcell.EventKey {
+	defer fe.Draw()
+	preview := fe.preview
+	switch event.Key() {
+	case tcell.KeyEscape, tcell.KeyTab:
+		fe.unfocusPreview()
+		return nil
+	case tcell.KeyCtrlE:
+		preview.scroll(1)
+		return nil
+	case tcell.KeyCtrlY:
+		preview.scroll(-1)
+		return nil
+	case tcell.KeyCtrlD:
+		preview.scroll(preview.pageHeight())
+		return nil
+	case tcell.KeyCtrlU:
+		preview.scroll(-preview.pageHeight())
+		return nil
+	case tcell.KeyRune:
+	default:
+		// Arrow and page keys are handled by the text view
+		return event
+	}
+	switch event.Rune() {
+	case 'j':
+		preview.scroll(1)
+	case 'k':
+		preview.scroll(-1)
+	case 'J':
+		preview.scroll(preview.pageHeight())
+	case 'K':
+		preview.scroll(-preview.pageHeight())
+	case 'g':
+		preview.scrollToBeginning()
+	case 'G':
+		preview.scrollToEnd()
+	case 't':
+		preview.toggleTail()
+	case 'x':
+		preview.toggleHex()
+	case '/':
+		fe.handleFooterInput("/")
+	case ':':
+		fe.handleFooterInput(":")
+	case 'n':
+		preview.nextMatch(false)
+	case 'N':
+		preview.nextMatch(true)
+	case '#':
+		preview.setLineNumbers(fe.togglePreviewOption("preview.line_numbers"))
+	case 'w':
+		preview.options.Wrap = fe.togglePreviewOption("preview.wrap")
+		preview.textView.SetWrap(preview.options.Wrap && !preview.isHex)
+	case 'q', 'h':
+		fe.unfocusPreview()
+	}
+	return nil
+}
@@ -4,10 +4,11 @@ import (
 	"bufio"
 	"os"
 	"path/filepath"
-	"slices"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/thilobro/gofileyourself/internal/helper"
+	"github.com/thilobro/gofileyourself/internal/opener"
 	"github.com/thilobro/gofileyourself/internal/theme"
 	"github.com/thilobro/gofileyourself/internal/widget"
 
@@ -21,6 +22,7 @@ type Finder struct {
 	rootFlex             *tview.Flex
 	footer               *tview.InputField
 	fileList             *tview.List
+	entries              map[string]helper.Entry
 	searchedList         *tview.List
 	selectedList         tview.Primitive
 	currentFocusedWidget tview.Primitive
@@ -72,7 +74,7 @@ func (finder *Finder) setCurrentLine(lineIndex int) error {
 			SetDynamicColors(true).
 			SetRegions(true).
 			SetWordWrap(true)
-		textView.SetText("[gray::]No matches...[-::]")
+		textView.SetText(theme.Tag(theme.Current().Muted, "") + "No matches...[-::]")
 		finder.selectedList = textView
 		return nil
 	}
@@ -92,13 +94,13 @@ func (finder *Finder) setSelectedDirectory(selectedPath string) error {
 	}
 	selectedDirectoryIndex := 0
 
-	newSelectedList, err := helper.LoadDirectory(selectedPath, finder.context.ShowHiddenFiles, false, []string{})
+	newSelectedList, err := helper.LoadDirectory(selectedPath, helper.NewListOptions(finder.context.Config), false, []string{})
 	if err != nil {
 		return err
 	}
 
 	if newSelectedList == nil {
-		finder.selectedList, err = helper.LoadFilePreview(selectedPath)
+		finder.selectedList, err = helper.LoadFilePreview(selectedPath, finder.context.Config.Preview)
 		if err != nil {
 			return err
 		}
@@ -117,7 +119,7 @@ func (finder *Finder) SetupKeyBindings() {
 			finder.showRecentHistory()
 			return nil
 		case tcell.KeyCtrlH:
-			finder.context.ShowHiddenFiles = !finder.context.ShowHiddenFiles
+			finder.context.Config.ShowHidden = !finder.context.Config.ShowHidden
 
 			// Remember current selection before refresh
 			_, currentName := finder.searchedList.GetItemText(finder.searchedList.GetCurrentItem())
@@ -151,7 +153,7 @@ func (finder *Finder) SetupKeyBindings() {
 				finder.context.OnWidgetResult(widget.Find, filePath)
 				return nil
 			}
-			helper.OpenInNvim(filePath, finder.context.ChooseFilePath, finder.context.App, finder.context.Config.HistoryLen)
+			opener.OpenDefault(filePath, finder.context.ChooseFilePath, finder.context.App, finder.context.Config)
 			return nil
 		}
 
@@ -252,17 +254,18 @@ func (finder *Finder) fuzzySearch(text string) {
 
 	// Create new list with matches
 	newList := tview.NewList().ShowSecondaryText(false)
-	line := ""
 	for _, match := range matches {
-		for i := 0; i < len(match.Str); i++ {
-			if slices.Contains(allMatchedIndexes[match.Str], i) {
-				line = line + "[red::b]" + string(match.Str[i]) + "[-::-]"
-			} else {
-				line = line + string(match.Str[i])
-			}
+		entry, isEntry := finder.entries[match.Str]
+		if !isEntry {
+			entry = helper.Entry{Name: match.Str}
+		}
+		// Matched indexes are the byte offsets of matched runes
+		locations := [][]int{}
+		for _, index := range allMatchedIndexes[match.Str] {
+			_, size := utf8.DecodeRuneInString(match.Str[index:])
+			locations = append(locations, []int{index, index + size})
 		}
-		newList.AddItem(line, match.Str, 0, nil)
-		line = ""
+		newList.AddItem(entry.Highlight(locations), match.Str, 0, nil)
 	}
 
 	if len(matches) > 0 {
@@ -281,11 +284,15 @@ func (finder *Finder) fuzzySearch(text string) {
 
 func (finder *Finder) resetFileList() error {
 	finder.fileList.Clear()
-	fileList, err := helper.LoadDirectory(finder.context.CurrentPath, finder.context.ShowHiddenFiles, true, []string{})
+	entries, err := helper.ListEntries(finder.context.CurrentPath, helper.NewListOptions(finder.context.Config), true, []string{})
 	if err != nil {
 		return err
 	}
-	finder.fileList = fileList
+	finder.fileList = helper.NewEntryList(entries)
+	finder.entries = make(map[string]helper.Entry, len(entries))
+	for _, entry := range entries {
+		finder.entries[entry.Name] = entry
+	}
 	return nil
 }
 
@@ -321,24 +328,23 @@ func (finder *Finder) Run() error {
 }
 
 func (finder *Finder) applyTheme() {
-	explorerTheme := theme.GetExplorerTheme()
+	explorerTheme := theme.Current()
 
 	// Set global background through root flex
-	finder.rootFlex.SetBackgroundColor(explorerTheme.Bg0)
+	finder.rootFlex.SetBackgroundColor(explorerTheme.Background)
 
 	// Style the lists
 	finder.searchedList.
-		SetMainTextColor(explorerTheme.Fg1).
-		SetSelectedTextColor(explorerTheme.Black).
-		SetSelectedBackgroundColor(explorerTheme.Aqua).
-		SetBackgroundColor(explorerTheme.Bg0)
+		SetMainTextColor(explorerTheme.Text).
+		SetSelectedStyle(theme.SelectedStyle(explorerTheme.Selection)).
+		SetBackgroundColor(explorerTheme.Background)
 
 	// Style the footer
 	if finder.footer != nil {
 		finder.footer.
-			SetFieldBackgroundColor(explorerTheme.Bg1).
-			SetFieldTextColor(explorerTheme.Fg0).
-			SetBackgroundColor(explorerTheme.Bg0).
+			SetFieldBackgroundColor(explorerTheme.Panel).
+			SetFieldTextColor(explorerTheme.TextStrong).
+			SetBackgroundColor(explorerTheme.Background).
 			SetBorder(true).Blur()
 	}
 }
@@ -350,8 +356,7 @@ func (finder *Finder) GetInputCapture() func(*tcell.EventKey) *tcell.EventKey {
 
 func (finder *Finder) showRecentHistory() {
 	finder.fileList.Clear()
-	historyPath := filepath.Join(os.Getenv("HOME"), ".gofileyourselfhistory")
-	historyFile, err := os.Open(historyPath)
+	historyFile, err := os.Open(finder.context.Config.Paths.History)
 	if err != nil {
 		return
 	}
@@ -3,44 +3,173 @@ package formatter
 import (
 	"fmt"
 	"io"
+	"strings"
 
 	"github.com/thilobro/gofileyourself/internal/theme"
 
 	"github.com/alecthomas/chroma"
 	"github.com/alecthomas/chroma/formatters"
-	"github.com/alecthomas/chroma/styles"
-	"github.com/gdamore/tcell"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
 )
 
+// HEX_BYTES_PER_LINE is the number of bytes in a line of a hex dump
+const HEX_BYTES_PER_LINE = 16
+
 type TviewFormatter struct{}
 
-func (f *TviewFormatter) Format(w io.Writer, style *chroma.Style, iterator chroma.Iterator) error {
+// Segment is text of one style within a line
+type Segment struct {
+	// Tag is the tview tag of the style, empty for the colors of the text view
+	Tag  string
+	Text string
+}
+
+// Line is a line of highlighted text without the line break
+type Line []Segment
+
+// String returns the line with tview tags. Text is escaped per segment, so
+// that source code like arr[red] is not taken for a tag.
+func (line Line) String() string {
+	var text strings.Builder
+	for _, segment := range line {
+		text.WriteString(segment.Tag + tview.Escape(segment.Text))
+	}
+	return text.String()
+}
+
+// add appends text of a style, to the last segment if it has the same one
+func (line Line) add(tag string, text string) Line {
+	if text == "" {
+		return line
+	}
+	if len(line) > 0 && line[len(line)-1].Tag == tag {
+		line[len(line)-1].Text += text
+		return line
+	}
+	return append(line, Segment{Tag: tag, Text: text})
+}
+
+// Plain returns the text of the line without styles
+func (line Line) Plain() string {
+	var text strings.Builder
+	for _, segment := range line {
+		text.WriteString(segment.Text)
+	}
+	return text.String()
+}
+
+// Lines splits the tokens into lines of segments. Consecutive tokens with the
+// same style are merged into one segment, and every line starts with the tag
+// of its first segment, so lines can be shown on their own. Tokens without a
+// color have the text color.
+func Lines(style *chroma.Style, textColor tcell.Color, iterator chroma.Iterator) []Line {
+	lines := []Line{{}}
 	for token := iterator(); token != chroma.EOF; token = iterator() {
-		entry := style.Get(token.Type)
-		if entry.Colour.IsSet() {
-			// Convert chroma color to tcell color
-			color := chromaColorToTcell(entry.Colour)
-			r, g, b := color.RGB()
-			fmt.Fprintf(w, "[#%02x%02x%02x]%s", r, g, b, token.Value)
-		} else {
-			fmt.Fprint(w, token.Value)
+		tag := styleTag(style.Get(token.Type), textColor)
+		for i, text := range strings.Split(token.Value, "\n") {
+			if i > 0 {
+				lines = append(lines, Line{})
+			}
+			lines[len(lines)-1] = lines[len(lines)-1].add(tag, text)
+		}
+	}
+	// Text ending with a line break has no further line
+	if len(lines) > 1 && len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// HexLines formats data like hexdump -C: the offset of every line, followed
+// by its bytes in hex and as ASCII. offset is the position of data in the
+// file. Null bytes are muted, bytes outside of printable ASCII accented in
+// the colors of hexTheme.
+func HexLines(data []byte, offset int64, hexTheme *theme.Theme) []Line {
+	lines := []Line{}
+	mutedTag := theme.Tag(hexTheme.Muted, "")
+	for start := 0; start < len(data); start += HEX_BYTES_PER_LINE {
+		bytes := data[start:min(start+HEX_BYTES_PER_LINE, len(data))]
+		line := Line{{Tag: mutedTag, Text: fmt.Sprintf("%08x  ", offset+int64(start))}}
+		for i := 0; i < HEX_BYTES_PER_LINE; i++ {
+			tag, text := mutedTag, "   "
+			if i < len(bytes) {
+				tag, text = byteTag(bytes[i], hexTheme), fmt.Sprintf("%02x ", bytes[i])
+			}
+			if i == HEX_BYTES_PER_LINE/2-1 {
+				text += " "
+			}
+			line = line.add(tag, text)
+		}
+		line = line.add(mutedTag, " |")
+		for _, b := range bytes {
+			character := "."
+			if b >= ' ' && b <= '~' {
+				character = string(rune(b))
+			}
+			line = line.add(byteTag(b, hexTheme), character)
+		}
+		lines = append(lines, line.add(mutedTag, "|"))
+	}
+	return lines
+}
+
+// byteTag returns the tag of a byte in a hex dump
+func byteTag(b byte, hexTheme *theme.Theme) string {
+	switch {
+	case b == 0:
+		return theme.Tag(hexTheme.Muted, "")
+	case b >= ' ' && b <= '~':
+		return theme.Tag(hexTheme.Text, "")
+	}
+	return theme.Tag(hexTheme.Accent, "")
+}
+
+// Format writes the tokens with tview [fg:bg:attrs] tags
+func (f *TviewFormatter) Format(w io.Writer, style *chroma.Style, iterator chroma.Iterator) error {
+	for _, line := range Lines(style, theme.Current().Text, iterator) {
+		if _, err := fmt.Fprintln(w, line.String()); err != nil {
+			return err
 		}
 	}
-	// Reset color at the end
-	fmt.Fprint(w, "[white]")
-	return nil
+	// Reset to the colors of the text view
+	_, err := fmt.Fprint(w, "[-:-:-]")
+	return err
 }
 
-// chromaColorToTcell converts a chroma.Colour to tcell.Color
-func chromaColorToTcell(c chroma.Colour) tcell.Color {
-	if !c.IsSet() {
-		return tcell.ColorWhite
+// styleTag converts a style entry to a tview tag with colors quantized to
+// the color depth. Unset colors fall back to textColor and the background of
+// the text view.
+func styleTag(entry chroma.StyleEntry, textColor tcell.Color) string {
+	foreground := theme.ColorName(textColor)
+	if entry.Colour.IsSet() {
+		foreground = theme.ColorName(colourToTcell(entry.Colour))
+	}
+	background := "-"
+	if entry.Background.IsSet() {
+		background = theme.ColorName(colourToTcell(entry.Background))
+	}
+	attributes := ""
+	if entry.Bold == chroma.Yes {
+		attributes += "b"
+	}
+	if entry.Italic == chroma.Yes {
+		attributes += "i"
 	}
+	if entry.Underline == chroma.Yes {
+		attributes += "u"
+	}
+	if attributes == "" {
+		attributes = "-"
+	}
+	return "[" + foreground + ":" + background + ":" + attributes + "]"
+}
+
+func colourToTcell(c chroma.Colour) tcell.Color {
 	return tcell.NewRGBColor(int32(c.Red()), int32(c.Green()), int32(c.Blue()))
 }
 
 // Register the formatter
 func RegisterCustomFormatter() {
 	formatters.Register("tview", &TviewFormatter{})
-	styles.Register(theme.GetFormatterStyle())
 }
@@ -2,27 +2,39 @@ package helper
 
 import (
 	"bytes"
+	"cmp"
+	"context"
 	"errors"
+	"fmt"
 	"io"
+	"io/fs"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"slices"
-	"sort"
 	"strconv"
 	"strings"
 	"unicode/utf8"
 
-	"github.com/alecthomas/chroma/formatters"
+	"github.com/thilobro/gofileyourself/internal/archive"
+	"github.com/thilobro/gofileyourself/internal/config"
+	"github.com/thilobro/gofileyourself/internal/editor"
+	"github.com/thilobro/gofileyourself/internal/formatter"
+	"github.com/thilobro/gofileyourself/internal/icons"
+	"github.com/thilobro/gofileyourself/internal/lscolors"
+	"github.com/thilobro/gofileyourself/internal/theme"
+
 	"github.com/alecthomas/chroma/lexers"
-	"github.com/alecthomas/chroma/styles"
+	gostring "github.com/boyter/go-string"
 	"github.com/otiai10/copy"
 	"github.com/rivo/tview"
 )
 
+// ICON_COLUMN_WIDTH is the width in cells of the icon in front of the names
+const ICON_COLUMN_WIDTH = 2
+
 // FindExactItem is a helper function that searches for an item in a list
 func FindExactItem(list *tview.List, searchTerm string) int {
-	matchingIndeces := list.FindItems(searchTerm, "", false, true)
+	matchingIndeces := list.FindItems("", searchTerm, false, true)
 	if len(matchingIndeces) == 1 {
 		return matchingIndeces[0]
 	}
@@ -52,8 +64,195 @@ func CopyFile(src string, dst string) error {
 	return err
 }
 
+// ListOptions control which entries a directory listing shows and their order
+type ListOptions struct {
+	ShowHidden bool
+	Sort       string
+	Reverse    bool
+	DirsFirst  bool
+	// Icons are shown in front of the names if set
+	Icons *icons.Set
+	// Colors style the names, the ones of $LS_COLORS or the active theme if
+	// not set
+	Colors *lscolors.Colors
+}
+
+// NewListOptions returns the listing options set in the config
+func NewListOptions(config *config.Config) ListOptions {
+	options := ListOptions{
+		ShowHidden: config.ShowHidden,
+		Sort:       config.Sort,
+		Reverse:    config.SortReverse,
+		DirsFirst:  config.DirsFirst,
+		// Taken now, so that listings in the background do not read the
+		// active theme while it changes
+		Colors: lscolors.Current(),
+	}
+	if config.Icons.Enabled {
+		options.Icons = icons.Cached(config.Icons.Extensions, config.Icons.Files, config.Icons.Directories)
+	}
+	return options
+}
+
+// sortEntries sorts directory entries by the order of the options, falling
+// back to the name for equal keys
+func sortEntries(entries []os.DirEntry, options ListOptions) {
+	infos := make(map[string]os.FileInfo, len(entries))
+	if options.Sort == config.SortSize || options.Sort == config.SortMtime {
+		for _, entry := range entries {
+			if info, err := entry.Info(); err == nil {
+				infos[entry.Name()] = info
+			}
+		}
+	}
+	compare := func(a os.DirEntry, b os.DirEntry) int {
+		switch options.Sort {
+		case config.SortNatural:
+			return compareNatural(a.Name(), b.Name())
+		case config.SortSize:
+			if infoA, infoB := infos[a.Name()], infos[b.Name()]; infoA != nil && infoB != nil && infoA.Size() != infoB.Size() {
+				// Largest first
+				return cmp.Compare(infoB.Size(), infoA.Size())
+			}
+		case config.SortMtime:
+			if infoA, infoB := infos[a.Name()], infos[b.Name()]; infoA != nil && infoB != nil && !infoA.ModTime().Equal(infoB.ModTime()) {
+				// Newest first
+				return infoB.ModTime().Compare(infoA.ModTime())
+			}
+		case config.SortExt:
+			if order := strings.Compare(filepath.Ext(a.Name()), filepath.Ext(b.Name())); order != 0 {
+				return order
+			}
+		}
+		return strings.Compare(a.Name(), b.Name())
+	}
+	slices.SortStableFunc(entries, func(a os.DirEntry, b os.DirEntry) int {
+		if options.DirsFirst && a.IsDir() != b.IsDir() {
+			if a.IsDir() {
+				return -1
+			}
+			return 1
+		}
+		if options.Reverse {
+			return compare(b, a)
+		}
+		return compare(a, b)
+	})
+}
+
+// compareNatural compares names with runs of digits ordered by their numeric
+// value, so that file2 comes before file10
+func compareNatural(a string, b string) int {
+	for a != "" && b != "" {
+		digitsA := len(a) - len(strings.TrimLeft(a, "0123456789"))
+		digitsB := len(b) - len(strings.TrimLeft(b, "0123456789"))
+		if digitsA > 0 && digitsB > 0 {
+			numberA := strings.TrimLeft(a[:digitsA], "0")
+			numberB := strings.TrimLeft(b[:digitsB], "0")
+			if order := cmp.Compare(len(numberA), len(numberB)); order != 0 {
+				return order
+			}
+			if order := strings.Compare(numberA, numberB); order != 0 {
+				return order
+			}
+			a, b = a[digitsA:], b[digitsB:]
+			continue
+		}
+		if a[0] != b[0] {
+			return cmp.Compare(a[0], b[0])
+		}
+		a, b = a[1:], b[1:]
+	}
+	return cmp.Compare(len(a), len(b))
+}
+
+// Entry is a file of a directory listing with the decorations of its list
+// item
+type Entry struct {
+	// Name is the path relative to the listed directory
+	Name string
+	// Tag colors the name, see lscolors
+	Tag string
+	// Suffix is / for directories and * for executables
+	Suffix string
+	// Icon is empty unless icons are enabled
+	Icon   string
+	Marked bool
+}
+
+// Text returns the text of the list item, with the occurrences of search in
+// the name highlighted
+func (entry Entry) Text(search string) string {
+	if search == "" {
+		return entry.Highlight(nil)
+	}
+	return entry.Highlight(gostring.IndexAll(entry.Name, search, -1))
+}
+
+// Highlight returns the text of the list item with the byte ranges of the
+// name in locations highlighted
+func (entry Entry) Highlight(locations [][]int) string {
+	tag := entry.Tag
+	if tag == "" {
+		tag = "[-:-:-]"
+	}
+	text := ""
+	if entry.Marked {
+		text = theme.Tag(theme.Current().Mark, "b") + "m>[-:-:-] "
+	}
+	text += tag
+	if entry.Icon != "" {
+		text += iconColumn(entry.Icon)
+	}
+	slices.SortFunc(locations, func(a []int, b []int) int {
+		return cmp.Compare(a[0], b[0])
+	})
+	position := 0
+	for _, location := range locations {
+		if location[0] < position || location[1] > len(entry.Name) {
+			continue
+		}
+		text += tview.Escape(entry.Name[position:location[0]])
+		text += theme.Tag(theme.Current().SearchHighlight, "b") + tview.Escape(entry.Name[location[0]:location[1]]) + "[-:-:-]" + tag
+		position = location[1]
+	}
+	return text + tview.Escape(entry.Name[position:]) + "[-:-:-]" + entry.Suffix
+}
+
+// iconColumn pads an icon to ICON_COLUMN_WIDTH cells, so that the names
+// after it line up. Icons as wide as the column are not separated from the
+// name.
+func iconColumn(icon string) string {
+	icon = tview.Escape(icon)
+	padding := ICON_COLUMN_WIDTH - tview.TaggedStringWidth(icon)
+	if padding < 0 {
+		padding = 0
+	}
+	return icon + strings.Repeat(" ", padding)
+}
+
+// NewEntryList creates a list of entries. The secondary text of an item is
+// the plain name, which FindExactItem looks up.
+func NewEntryList(entries []Entry) *tview.List {
+	list := tview.NewList().ShowSecondaryText(false)
+	for _, entry := range entries {
+		list.AddItem(entry.Text(""), entry.Name, 0, nil)
+	}
+	return list
+}
+
 // LoadDirectory is a helper function that loads directory contents into a list
-func LoadDirectory(path string, showHiddenFiles bool, recursive bool, markedItems []string) (*tview.List, error) {
+func LoadDirectory(path string, options ListOptions, recursive bool, markedItems []string) (*tview.List, error) {
+	entries, err := ListEntries(path, options, recursive, markedItems)
+	if entries == nil || err != nil {
+		return nil, err
+	}
+	return NewEntryList(entries), nil
+}
+
+// ListEntries reads the entries of a directory. It returns nil if path is not
+// a directory.
+func ListEntries(path string, options ListOptions, recursive bool, markedItems []string) ([]Entry, error) {
 	fileInfo, err := os.Stat(path)
 	if err != nil {
 		return nil, err
@@ -63,7 +262,11 @@ func LoadDirectory(path string, showHiddenFiles bool, recursive bool, markedItem
 		return nil, nil
 	}
 
-	list := tview.NewList().ShowSecondaryText(false)
+	entries := []Entry{}
+	colors := options.Colors
+	if colors == nil {
+		colors = lscolors.Current()
+	}
 
 	var processDir func(dirPath string) error
 	processDir = func(dirPath string) error {
@@ -78,21 +281,13 @@ func LoadDirectory(path string, showHiddenFiles bool, recursive bool, markedItem
 		fileSlice := make([]os.DirEntry, 0)
 		for _, file := range files {
 			fileName := file.Name()
-			if !showHiddenFiles && len(fileName) > 0 && fileName[0] == '.' {
+			if !options.ShowHidden && len(fileName) > 0 && fileName[0] == '.' {
 				continue
 			}
 			fileSlice = append(fileSlice, file)
 		}
 
-		// Sort: directories first, then alphabetically
-		sort.Slice(fileSlice, func(i, j int) bool {
-			iIsDir := fileSlice[i].IsDir()
-			jIsDir := fileSlice[j].IsDir()
-			if iIsDir == jIsDir {
-				return fileSlice[i].Name() < fileSlice[j].Name()
-			}
-			return iIsDir
-		})
+		sortEntries(fileSlice, options)
 
 		for _, file := range fileSlice {
 			info, err := file.Info()
@@ -107,24 +302,35 @@ func LoadDirectory(path string, showHiddenFiles bool, recursive bool, markedItem
 				continue
 			}
 
-			displayName := relPath
-			if slices.Contains(markedItems, absPath) {
-				displayName = "m> " + displayName
+			entry := Entry{
+				Name:   relPath,
+				Tag:    colors.Tag(absPath, info),
+				Marked: slices.Contains(markedItems, absPath),
+			}
+			if options.Icons != nil {
+				isDir := file.IsDir()
+				// Links to directories get the icons of directories
+				if file.Type()&fs.ModeSymlink != 0 {
+					if targetInfo, err := os.Stat(absPath); err == nil {
+						isDir = targetInfo.IsDir()
+					}
+				}
+				entry.Icon = options.Icons.Icon(relPath, isDir)
 			}
 			if file.IsDir() {
-				displayName += "/"
+				entry.Suffix = "/"
 				if recursive {
 					// Recursively process subdirectories
-					err := processDir(filepath.Join(dirPath, file.Name()))
+					err := processDir(absPath)
 					if err != nil {
 						return err
 					}
 				}
-			} else if info.Mode()&0o111 != 0 {
-				displayName += "*"
+			} else if info.Mode().IsRegular() && info.Mode()&0o111 != 0 {
+				entry.Suffix = "*"
 			}
 
-			list.AddItem(displayName, relPath, 0, nil)
+			entries = append(entries, entry)
 		}
 		return nil
 	}
@@ -134,7 +340,7 @@ func LoadDirectory(path string, showHiddenFiles bool, recursive bool, markedItem
 		return nil, err
 	}
 
-	return list, nil
+	return entries, nil
 }
 
 func IsTextFile(path string) bool {
@@ -160,80 +366,251 @@ func IsTextFile(path string) bool {
 	return utf8.Valid(buffer[:n])
 }
 
-// LoadFilePreview is a helper function that creates a text view for file contents
-func LoadFilePreview(path string) (*tview.TextView, error) {
+// LoadFilePreview is a helper function that creates a text view for the
+// first chunk of a file, syntax highlighted if enabled in options, the
+// entries of an archive or a hex dump of other files that are not text
+func LoadFilePreview(path string, options config.PreviewConfig) (*tview.TextView, error) {
 	// Create text view
 	textView := tview.NewTextView().
 		SetDynamicColors(true).
 		SetRegions(true).
 		SetWordWrap(true)
 
-	if IsTextFile(path) {
-		content, err := os.ReadFile(path)
-		if err != nil {
-			return nil, err
-		}
+	currentTheme := theme.Current()
+	chunk, err := LoadFileChunk(path, 0, false, options, currentTheme)
+	if err == nil && chunk == nil {
+		chunk, err = LoadArchiveChunk(context.Background(), path, options, currentTheme)
+	}
+	if err == nil && chunk == nil {
+		chunk, err = LoadHexChunk(path, false, options, currentTheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+	text := make([]string, len(chunk.Lines))
+	for i, line := range chunk.Lines {
+		text[i] = line.String()
+	}
+	textView.SetText(strings.Join(text, "\n") + "[-:-:-]")
+	return textView, nil
+}
 
-		lexer := lexers.Match(path)
-		if lexer == nil {
-			lexer = lexers.Fallback
-		}
+// FileChunk is a part of a text file split into lines
+type FileChunk struct {
+	Lines []formatter.Line
+	// Start and End are the byte offsets of the chunk in the file
+	Start int64
+	End   int64
+	// Size is the size of the file when the chunk was read
+	Size int64
+}
 
-		style := styles.Get("gruvbox")
-		if style == nil {
-			style = styles.Fallback
-		}
+// LoadFileChunk reads the lines of a text file from offset on, or the last
+// lines with isTail, limited to the max_bytes and max_lines of options.
+// Lines cut by the byte limit are left to the next chunk, unless a single
+// line exceeds it. Files that are not text have no chunk. The lines are
+// highlighted with the syntax style of chunkTheme.
+func LoadFileChunk(path string, offset int64, isTail bool, options config.PreviewConfig, chunkTheme *theme.Theme) (*FileChunk, error) {
+	if !IsTextFile(path) {
+		return nil, nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	chunk := &FileChunk{Start: offset, Size: info.Size()}
+	maxBytes := int64(options.MaxBytes)
+	if maxBytes <= 0 {
+		maxBytes = chunk.Size
+	}
+	if isTail {
+		chunk.Start = max(chunk.Size-maxBytes, 0)
+	}
+	buffer := make([]byte, max(min(maxBytes, chunk.Size-chunk.Start), 0))
+	n, err := file.ReadAt(buffer, chunk.Start)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	text := string(buffer[:n])
+	chunk.End = chunk.Start + int64(n)
 
-		formatter := formatters.Get("tview")
-		if formatter == nil {
-			formatter = formatters.Fallback
+	if index := strings.IndexByte(text, '\n'); isTail && chunk.Start > 0 && index >= 0 && index < len(text)-1 {
+		text = text[index+1:]
+		chunk.Start += int64(index + 1)
+	}
+	if index := strings.LastIndexByte(text, '\n'); !isTail && chunk.End < chunk.Size && index >= 0 {
+		text = text[:index+1]
+		chunk.End = chunk.Start + int64(index+1)
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if len(lines) > 1 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if options.MaxLines > 0 && len(lines) > options.MaxLines {
+		if isTail {
+			chunk.Start += int64(len(strings.Join(lines[:len(lines)-options.MaxLines], "")))
+			lines = lines[len(lines)-options.MaxLines:]
+		} else {
+			lines = lines[:options.MaxLines]
+			chunk.End = chunk.Start + int64(len(strings.Join(lines, "")))
 		}
+	}
 
-		iterator, err := lexer.Tokenise(nil, string(content))
-		if err != nil {
-			return nil, err
+	highlight := options.Highlight && (options.HighlightMaxBytes <= 0 || chunk.Size <= int64(options.HighlightMaxBytes))
+	chunk.Lines, err = splitLines(path, strings.Join(lines, ""), highlight, chunkTheme)
+	if err != nil {
+		return nil, err
+	}
+	return chunk, nil
+}
+
+// splitLines splits text into lines, syntax highlighted by the language of
+// path in the syntax style of lineTheme if highlight is set
+func splitLines(path string, text string, highlight bool, lineTheme *theme.Theme) ([]formatter.Line, error) {
+	if !highlight {
+		lines := []formatter.Line{}
+		for _, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+			lines = append(lines, formatter.Line{{Text: line}})
 		}
+		return lines, nil
+	}
 
-		var buf bytes.Buffer
-		err = formatter.Format(&buf, style, iterator)
-		if err != nil {
-			return nil, err
+	lexer := lexers.Match(path)
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+	iterator, err := lexer.Tokenise(nil, text)
+	if err != nil {
+		return nil, err
+	}
+	return formatter.Lines(lineTheme.SyntaxStyle, lineTheme.Text, iterator), nil
+}
+
+// LoadHexChunk reads the first or, with isTail, the last hex_max_bytes of a
+// file as lines of a hex dump in the colors of chunkTheme
+func LoadHexChunk(path string, isTail bool, options config.PreviewConfig, chunkTheme *theme.Theme) (*FileChunk, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	chunk := &FileChunk{Size: info.Size()}
+	maxBytes := int64(options.HexMaxBytes)
+	if maxBytes <= 0 {
+		maxBytes = chunk.Size
+	}
+	if isTail {
+		// Lines of the tail start at the same offsets as the ones of the head
+		chunk.Start = max(chunk.Size-maxBytes, 0)
+		chunk.Start += (formatter.HEX_BYTES_PER_LINE - chunk.Start%formatter.HEX_BYTES_PER_LINE) % formatter.HEX_BYTES_PER_LINE
+	}
+	buffer := make([]byte, max(min(maxBytes, chunk.Size-chunk.Start), 0))
+	n, err := file.ReadAt(buffer, chunk.Start)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	chunk.End = chunk.Start + int64(n)
+	chunk.Lines = formatter.HexLines(buffer[:n], chunk.Start, chunkTheme)
+	return chunk, nil
+}
+
+// LoadArchiveChunk lists the entries of a zip or tar archive, up to the
+// archive_max_entries of options, below a line with their count and total
+// size, in the colors of chunkTheme. Files that are not archives have no
+// chunk.
+func LoadArchiveChunk(loadContext context.Context, path string, options config.PreviewConfig, chunkTheme *theme.Theme) (*FileChunk, error) {
+	listing, err := archive.List(loadContext, path, options.ArchiveMaxEntries)
+	if err != nil || listing == nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	mutedTag := theme.Tag(chunkTheme.Muted, "")
+	summary := strings.ToUpper(listing.Format) + " archive, "
+	if listing.IsCut {
+		summary += fmt.Sprintf("first %d entries, %s uncompressed, the rest is not read", listing.Count, FormatSize(listing.TotalSize))
+	} else {
+		summary += fmt.Sprintf("%d entries, %s uncompressed", listing.Count, FormatSize(listing.TotalSize))
+		if len(listing.Entries) < listing.Count {
+			summary += fmt.Sprintf(", the first %d are listed", len(listing.Entries))
 		}
-		textView.SetText(buf.String())
-		return textView, nil
 	}
-	textView.SetText("[gray::]No preview...[-::]")
-	return textView, nil
+	lines := []formatter.Line{{{Tag: theme.Tag(chunkTheme.TextStrong, "b"), Text: summary}}, {}}
+	for _, entry := range listing.Entries {
+		nameTag := theme.Tag(chunkTheme.Text, "")
+		if entry.Mode.IsDir() {
+			nameTag = theme.Tag(chunkTheme.Directory, "")
+		} else if entry.Mode&fs.ModeSymlink != 0 {
+			nameTag = theme.Tag(chunkTheme.Symlink, "")
+		}
+		line := formatter.Line{
+			{Tag: mutedTag, Text: entry.Mode.String() + " "},
+			{Tag: theme.Tag(chunkTheme.Text, ""), Text: fmt.Sprintf("%10s ", FormatSize(entry.Size))},
+			{Tag: mutedTag, Text: entry.ModTime.Format("2006-01-02 15:04") + "  "},
+			{Tag: nameTag, Text: entry.Name},
+		}
+		if entry.Link != "" {
+			line = append(line, formatter.Segment{Tag: mutedTag, Text: " -> " + entry.Link})
+		}
+		lines = append(lines, line)
+	}
+	return &FileChunk{Lines: lines, End: info.Size(), Size: info.Size()}, nil
 }
 
-// OpenInNvim is a helper function that opens a file in neovim
-func OpenInNvim(path string, selectedFilePath *string, app *tview.Application, maxHistoryLen int) error {
+// OpenInEditor is a helper function that opens a file in the configured
+// editor at the given line and column, or writes it to the chooser file
+func OpenInEditor(path string, line int, column int, selectedFilePath *string, app *tview.Application, config *config.Config) error {
 	if selectedFilePath == nil {
-		app.Suspend(func() {
-			cmd := exec.Command("nvim", path)
-			cmd.Stdin = os.Stdin
-			cmd.Stdout = os.Stdout
-			cmd.Stderr = os.Stderr
-			cmd.Run()
-		})
+		if err := editor.Resolve(config.Editor).Edit(app, path, line, column); err != nil {
+			return err
+		}
 	} else {
-		cmd := exec.Command("sh", "-c", "echo \""+path+"\" > "+*selectedFilePath)
-		cmd.Stdin = os.Stdin
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-		cmd.Run()
+		if err := os.WriteFile(*selectedFilePath, []byte(path+"\n"), 0o644); err != nil {
+			return err
+		}
 		app.Stop()
 	}
-	historyPath := filepath.Join(os.Getenv("HOME"), ".gofileyourselfhistory")
-	historyCmd := exec.Command("sh", "-c", "[ \"$(tail -n 1 "+historyPath+" 2>/dev/null)\" != "+path+" ] && echo \""+path+"\" >> "+historyPath)
-	historyCmd.Stdin = os.Stdin
-	historyCmd.Stdout = os.Stdout
-	historyCmd.Stderr = os.Stderr
-	historyCmd.Run()
-	TrimAndGetRecentFiles(historyPath, maxHistoryLen)
+	AddToHistory(path, config)
 	return nil
 }
 
+// AddToHistory appends an opened file to the history of recent files
+func AddToHistory(path string, config *config.Config) {
+	historyPath := config.Paths.History
+	if err := os.MkdirAll(filepath.Dir(historyPath), 0o700); err != nil {
+		return
+	}
+	// Opening the same file again does not add it twice in a row
+	content, err := os.ReadFile(historyPath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return
+	}
+	lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	if lines[len(lines)-1] != path {
+		file, err := os.OpenFile(historyPath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+		if err != nil {
+			return
+		}
+		_, err = file.WriteString(path + "\n")
+		file.Close()
+		if err != nil {
+			return
+		}
+	}
+	TrimAndGetRecentFiles(historyPath, config.HistoryLen)
+}
+
 func TrimAndGetRecentFiles(path string, maxHistoryLen int) []string {
 	content, err := os.ReadFile(path)
 	if err != nil {
@@ -242,19 +619,15 @@ func TrimAndGetRecentFiles(path string, maxHistoryLen int) []string {
 	lines := strings.Split(string(content), "\n")
 	lenLines := len(lines)
 	if lenLines > maxHistoryLen {
-		historyCmd := exec.Command("sh", "-c", "sed '1,"+strconv.Itoa(lenLines-maxHistoryLen)+"d' -i "+path)
-		historyCmd.Stdin = os.Stdin
-		historyCmd.Stdout = os.Stdout
-		historyCmd.Stderr = os.Stderr
-		historyCmd.Run()
-		return lines[lenLines-maxHistoryLen:]
+		lines = lines[lenLines-maxHistoryLen:]
+		os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0o600)
+		return lines
 	}
 	return lines
 }
 
-func GetRecentFile(fileIndex int, maxHistoryLen int) (string, error) {
-	historyPath := filepath.Join(os.Getenv("HOME"), ".gofileyourselfhistory")
-	lines := TrimAndGetRecentFiles(historyPath, maxHistoryLen)
+func GetRecentFile(fileIndex int, config *config.Config) (string, error) {
+	lines := TrimAndGetRecentFiles(config.Paths.History, config.HistoryLen)
 	lenLines := len(lines)
 	if fileIndex >= lenLines {
 		return "", errors.New("file index out of range")
@@ -277,6 +650,39 @@ func IsDirectoryEmpty(path string) (bool, error) {
 	return false, err
 }
 
+// DiskUsage counts the files and directories below path and sums up their sizes
+func DiskUsage(path string) (files int, dirs int, size int64) {
+	filepath.WalkDir(path, func(_ string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if entry.IsDir() {
+			dirs++
+			return nil
+		}
+		files++
+		if info, err := entry.Info(); err == nil {
+			size += info.Size()
+		}
+		return nil
+	})
+	return files, dirs, size
+}
+
+// FormatSize returns a human readable representation of a size in bytes
+func FormatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return strconv.FormatInt(size, 10) + " B"
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return strconv.FormatFloat(float64(size)/float64(div), 'f', 1, 64) + " " + string("KMGTPE"[exp]) + "iB"
+}
+
 func DeleteItem[T comparable](slice []T, element T) []T {
 	newSlice := make([]T, 0)
 	for _, v := range slice {
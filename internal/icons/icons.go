@@ -0,0 +1,206 @@
+package icons
+
+import (
+	"maps"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// DEFAULT_FILE and DEFAULT_DIRECTORY are shown for names without an icon
+const (
+	DEFAULT_FILE      = "" // nf-fa-file_o
+	DEFAULT_DIRECTORY = "" // nf-fa-folder
+)
+
+// Nerd Font glyphs shared by several names. All built-in glyphs are in the
+// private use area of the basic multilingual plane, which terminals draw one
+// cell wide.
+const (
+	archive  = "" // nf-fa-file_archive_o
+	audio    = "" // nf-fa-file_audio_o
+	image    = "" // nf-fa-file_image_o
+	video    = "" // nf-fa-file_video_o
+	config   = "" // nf-seti-config
+	terminal = "" // nf-oct-terminal
+	git      = "" // nf-dev-git
+	docker   = "" // nf-linux-docker
+	goIcon   = "" // nf-seti-go
+	rust     = "" // nf-dev-rust
+	npm      = "" // nf-dev-npm
+	text     = "" // nf-fa-file_text
+)
+
+var builtinExtensions = map[string]string{
+	"go":         goIcon,
+	"py":         "", // nf-seti-python
+	"js":         "", // nf-seti-javascript
+	"mjs":        "",
+	"ts":         "", // nf-seti-typescript
+	"jsx":        "", // nf-dev-react
+	"tsx":        "",
+	"rs":         rust,
+	"c":          "", // nf-custom-c
+	"h":          "", // nf-fa-h_square
+	"cpp":        "", // nf-custom-cpp
+	"cc":         "",
+	"hpp":        "",
+	"java":       "", // nf-dev-java
+	"rb":         "", // nf-dev-ruby
+	"php":        "", // nf-dev-php
+	"lua":        "", // nf-seti-lua
+	"vim":        "", // nf-custom-vim
+	"sh":         terminal,
+	"bash":       terminal,
+	"zsh":        terminal,
+	"fish":       terminal,
+	"md":         "", // nf-seti-markdown
+	"json":       "", // nf-seti-json
+	"yaml":       config,
+	"yml":        config,
+	"toml":       config,
+	"ini":        config,
+	"conf":       config,
+	"html":       "", // nf-dev-html5
+	"css":        "", // nf-dev-css3
+	"scss":       "", // nf-seti-sass
+	"sql":        "", // nf-dev-database
+	"db":         "",
+	"diff":       "", // nf-oct-diff
+	"patch":      "",
+	"lock":       "", // nf-fa-lock
+	"txt":        text,
+	"log":        text,
+	"pdf":        "", // nf-fa-file_pdf_o
+	"doc":        "", // nf-fa-file_word_o
+	"docx":       "",
+	"xls":        "", // nf-fa-file_excel_o
+	"xlsx":       "",
+	"csv":        "",
+	"ppt":        "", // nf-fa-file_powerpoint_o
+	"pptx":       "",
+	"png":        image,
+	"jpg":        image,
+	"jpeg":       image,
+	"gif":        image,
+	"bmp":        image,
+	"svg":        image,
+	"webp":       image,
+	"ico":        image,
+	"mp3":        audio,
+	"flac":       audio,
+	"wav":        audio,
+	"ogg":        audio,
+	"mp4":        video,
+	"mkv":        video,
+	"webm":       video,
+	"avi":        video,
+	"mov":        video,
+	"zip":        archive,
+	"tar":        archive,
+	"gz":         archive,
+	"tgz":        archive,
+	"xz":         archive,
+	"bz2":        archive,
+	"zst":        archive,
+	"7z":         archive,
+	"rar":        archive,
+	"deb":        archive,
+	"rpm":        archive,
+	"iso":        "", // nf-fae-disco
+	"exe":        "", // nf-fa-windows
+	"dockerfile": docker,
+}
+
+var builtinFiles = map[string]string{
+	"Makefile":           "", // nf-dev-gnu
+	"Dockerfile":         docker,
+	"docker-compose.yml": docker,
+	"go.mod":             goIcon,
+	"go.sum":             goIcon,
+	"Cargo.toml":         rust,
+	"Cargo.lock":         rust,
+	"package.json":       npm,
+	"package-lock.json":  npm,
+	".gitignore":         git,
+	".gitattributes":     git,
+	".gitmodules":        git,
+	"LICENSE":            "", // nf-fa-book
+	"README.md":          "", // nf-fa-info_circle
+	".bashrc":            terminal,
+	".zshrc":             terminal,
+}
+
+var builtinDirectories = map[string]string{
+	".git":         "", // nf-custom-folder_git
+	".github":      "", // nf-custom-folder_github
+	".config":      "", // nf-custom-folder_config
+	"node_modules": "", // nf-custom-folder_npm
+	"Desktop":      "", // nf-fa-desktop
+	"Documents":    "", // nf-fa-book
+	"Downloads":    "", // nf-fa-download
+	"Music":        "", // nf-fa-music
+	"Pictures":     "", // nf-fa-image
+	"Videos":       "", // nf-fa-video_camera
+}
+
+// Set maps file names to icons. Files are matched by their whole name
+// first, then by their extension.
+type Set struct {
+	Extensions  map[string]string
+	Files       map[string]string
+	Directories map[string]string
+}
+
+// New returns the built-in icons with the given ones added or replaced.
+// Extensions are written without the leading dot.
+func New(extensions map[string]string, files map[string]string, directories map[string]string) *Set {
+	set := &Set{
+		Extensions:  maps.Clone(builtinExtensions),
+		Files:       maps.Clone(builtinFiles),
+		Directories: maps.Clone(builtinDirectories),
+	}
+	for extension, icon := range extensions {
+		set.Extensions[strings.ToLower(strings.TrimPrefix(extension, "."))] = icon
+	}
+	maps.Copy(set.Files, files)
+	maps.Copy(set.Directories, directories)
+	return set
+}
+
+// lastSet is the set built by Cached for the icons of lastIcons
+var (
+	lastSet      *Set
+	lastIcons    [3]map[string]string
+	lastSetMutex sync.Mutex
+)
+
+// Cached returns the same set as New, which is built again only once the
+// given icons differ from the ones of the last call
+func Cached(extensions map[string]string, files map[string]string, directories map[string]string) *Set {
+	lastSetMutex.Lock()
+	defer lastSetMutex.Unlock()
+	if lastSet == nil || !maps.Equal(extensions, lastIcons[0]) || !maps.Equal(files, lastIcons[1]) || !maps.Equal(directories, lastIcons[2]) {
+		lastSet = New(extensions, files, directories)
+		lastIcons = [3]map[string]string{maps.Clone(extensions), maps.Clone(files), maps.Clone(directories)}
+	}
+	return lastSet
+}
+
+// Icon returns the icon of a file or directory by its base name
+func (set *Set) Icon(name string, isDir bool) string {
+	name = filepath.Base(name)
+	if isDir {
+		if icon, found := set.Directories[name]; found {
+			return icon
+		}
+		return DEFAULT_DIRECTORY
+	}
+	if icon, found := set.Files[name]; found {
+		return icon
+	}
+	if icon, found := set.Extensions[strings.ToLower(strings.TrimPrefix(filepath.Ext(name), "."))]; found {
+		return icon
+	}
+	return DEFAULT_FILE
+}
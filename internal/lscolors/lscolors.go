@@ -0,0 +1,237 @@
+package lscolors
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/thilobro/gofileyourself/internal/theme"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// ansiAttributes maps SGR codes to tview attributes
+var ansiAttributes = map[int]string{1: "b", 2: "d", 3: "i", 4: "u", 5: "l", 7: "r", 9: "s"}
+
+// Colors holds the styles of file types and file name patterns
+type Colors struct {
+	// types are keyed by the LS_COLORS codes, e.g. di for directories
+	types map[string]style
+	// patterns like *.go, later ones take precedence
+	patterns []pattern
+	// isLinkAsTarget colors symlinks like the file they point to (ln=target)
+	isLinkAsTarget bool
+}
+
+type pattern struct {
+	glob  string
+	style style
+}
+
+// style is kept apart from its tag, which depends on the color depth
+type style struct {
+	foreground tcell.Color
+	background tcell.Color
+	attributes string
+}
+
+func (s style) tag() string {
+	if s == (style{}) {
+		return ""
+	}
+	return "[" + theme.ColorName(s.foreground) + ":" + theme.ColorName(s.background) + ":" + s.attributes + "]"
+}
+
+var (
+	environmentColors     *Colors
+	environmentColorsOnce sync.Once
+)
+
+// Current returns the colors of $LS_COLORS, or the ones of the active theme
+// if it is not set
+func Current() *Colors {
+	environmentColorsOnce.Do(func() {
+		if value := os.Getenv("LS_COLORS"); value != "" {
+			environmentColors = Parse(value)
+		}
+	})
+	if environmentColors != nil {
+		return environmentColors
+	}
+	return FromTheme(theme.Current())
+}
+
+// Parse reads a value in the format of $LS_COLORS, e.g. di=01;34:*.go=32.
+// Unknown entries are ignored.
+func Parse(value string) *Colors {
+	colors := &Colors{types: map[string]style{}}
+	for _, entry := range strings.Split(value, ":") {
+		key, codes, isEntry := strings.Cut(entry, "=")
+		if !isEntry || key == "" {
+			continue
+		}
+		if key == "ln" && codes == "target" {
+			colors.isLinkAsTarget = true
+			continue
+		}
+		entryStyle := sgrStyle(codes)
+		if glob, isPattern := strings.CutPrefix(key, "*"); isPattern {
+			colors.patterns = append(colors.patterns, pattern{glob: strings.ToLower(glob), style: entryStyle})
+		} else {
+			colors.types[key] = entryStyle
+		}
+	}
+	return colors
+}
+
+// FromTheme colors the file types with the roles of a theme
+func FromTheme(t *theme.Theme) *Colors {
+	return &Colors{types: map[string]style{
+		"di": {foreground: t.Directory, attributes: "b"},
+		"ln": {foreground: t.Symlink},
+		"or": {foreground: t.BrokenLink},
+		"ex": {foreground: t.Executable, attributes: "b"},
+		"pi": {foreground: t.Special},
+		"so": {foreground: t.Special},
+		"bd": {foreground: t.Special, attributes: "b"},
+		"cd": {foreground: t.Special, attributes: "b"},
+		"su": {foreground: t.Danger, attributes: "r"},
+		"sg": {foreground: t.Danger, attributes: "r"},
+		"tw": {foreground: t.Directory, attributes: "bu"},
+		"ow": {foreground: t.Directory, attributes: "bu"},
+		"st": {foreground: t.Directory, attributes: "bu"},
+	}}
+}
+
+// Tag returns the color tag for a file, or "" if it is not styled. info is
+// the file itself, not the target of a symlink.
+func (colors *Colors) Tag(path string, info fs.FileInfo) string {
+	return colors.style(path, info).tag()
+}
+
+func (colors *Colors) style(path string, info fs.FileInfo) style {
+	mode := info.Mode()
+	if mode&fs.ModeSymlink != 0 {
+		target, err := filepath.EvalSymlinks(path)
+		var targetInfo fs.FileInfo
+		if err == nil {
+			targetInfo, err = os.Stat(target)
+		}
+		if err != nil {
+			if orphanStyle, isSet := colors.types["or"]; isSet {
+				return orphanStyle
+			}
+			return colors.types["ln"]
+		}
+		if colors.isLinkAsTarget {
+			return colors.style(target, targetInfo)
+		}
+		return colors.types["ln"]
+	}
+
+	var key string
+	switch {
+	case mode.IsDir():
+		isSticky := mode&fs.ModeSticky != 0
+		isOtherWritable := mode.Perm()&0o002 != 0
+		switch {
+		case isSticky && isOtherWritable:
+			key = "tw"
+		case isOtherWritable:
+			key = "ow"
+		case isSticky:
+			key = "st"
+		default:
+			key = "di"
+		}
+	case mode&fs.ModeNamedPipe != 0:
+		key = "pi"
+	case mode&fs.ModeSocket != 0:
+		key = "so"
+	case mode&fs.ModeCharDevice != 0:
+		key = "cd"
+	case mode&fs.ModeDevice != 0:
+		key = "bd"
+	case mode&fs.ModeSetuid != 0:
+		key = "su"
+	case mode&fs.ModeSetgid != 0:
+		key = "sg"
+	case mode&0o111 != 0:
+		key = "ex"
+	default:
+		// Like ls, only regular files are colored by their name
+		name := strings.ToLower(info.Name())
+		for i := len(colors.patterns) - 1; i >= 0; i-- {
+			if strings.HasSuffix(name, colors.patterns[i].glob) {
+				return colors.patterns[i].style
+			}
+		}
+		return colors.types["fi"]
+	}
+	if typeStyle, isSet := colors.types[key]; isSet {
+		return typeStyle
+	}
+	// Special directories and files fall back to the plain type
+	if mode.IsDir() {
+		return colors.types["di"]
+	}
+	if key == "su" || key == "sg" {
+		return colors.types["ex"]
+	}
+	return style{}
+}
+
+// sgrStyle converts SGR codes like 01;38;5;208 to a style
+func sgrStyle(codes string) style {
+	result := style{}
+	numbers := []int{}
+	for _, code := range strings.Split(codes, ";") {
+		number, err := strconv.Atoi(code)
+		if err != nil && code != "" {
+			return style{}
+		}
+		numbers = append(numbers, number)
+	}
+	for i := 0; i < len(numbers); i++ {
+		number := numbers[i]
+		switch {
+		case number >= 30 && number <= 37:
+			result.foreground = tcell.PaletteColor(number - 30)
+		case number >= 90 && number <= 97:
+			result.foreground = tcell.PaletteColor(number - 90 + 8)
+		case number >= 40 && number <= 47:
+			result.background = tcell.PaletteColor(number - 40)
+		case number >= 100 && number <= 107:
+			result.background = tcell.PaletteColor(number - 100 + 8)
+		case number == 38 || number == 48:
+			color, consumed := extendedColor(numbers[i+1:])
+			i += consumed
+			if number == 38 {
+				result.foreground = color
+			} else {
+				result.background = color
+			}
+		default:
+			result.attributes += ansiAttributes[number]
+		}
+	}
+	return result
+}
+
+// extendedColor reads a 256 color (5;n) or true color (2;r;g;b) argument and
+// returns the color and the number of codes it took
+func extendedColor(numbers []int) (tcell.Color, int) {
+	if len(numbers) >= 2 && numbers[0] == 5 {
+		if numbers[1] < 0 || numbers[1] > 255 {
+			return tcell.ColorDefault, 2
+		}
+		return tcell.PaletteColor(numbers[1]), 2
+	}
+	if len(numbers) >= 4 && numbers[0] == 2 {
+		return tcell.NewRGBColor(int32(numbers[1]&0xff), int32(numbers[2]&0xff), int32(numbers[3]&0xff)), 4
+	}
+	return tcell.ColorDefault, len(numbers)
+}
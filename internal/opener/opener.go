@@ -0,0 +1,206 @@
+package opener
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/thilobro/gofileyourself/internal/config"
+	"github.com/thilobro/gofileyourself/internal/helper"
+	"github.com/thilobro/gofileyourself/internal/shell"
+
+	"github.com/rivo/tview"
+)
+
+// Run modes of an opener rule
+const (
+	ModeForeground = "foreground"
+	ModeBackground = "background"
+	ModeDetached   = "detached"
+)
+
+// File describes the properties of a file that rules are matched against
+type File struct {
+	Path       string
+	Mime       string
+	IsText     bool
+	IsTerminal bool
+}
+
+// NewFile sniffs the mime type of the file at path
+func NewFile(path string) File {
+	return File{
+		Path:       path,
+		Mime:       DetectMime(path),
+		IsText:     helper.IsTextFile(path),
+		IsTerminal: isTerminal(),
+	}
+}
+
+// DetectMime detects the mime type from the first bytes of a file and falls
+// back to the extension when the content is not conclusive
+func DetectMime(path string) string {
+	detected := "application/octet-stream"
+	if file, err := os.Open(path); err == nil {
+		buffer := make([]byte, 512)
+		n, _ := file.Read(buffer)
+		file.Close()
+		detected = http.DetectContentType(buffer[:n])
+	}
+	detected, _, _ = strings.Cut(detected, ";")
+	if detected == "application/octet-stream" || detected == "text/plain" {
+		if byExtension := mime.TypeByExtension(filepath.Ext(path)); byExtension != "" {
+			detected, _, _ = strings.Cut(byExtension, ";")
+		}
+	}
+	return detected
+}
+
+// Rules returns the configured rules followed by the built-in rules, which
+// open text files in the editor and everything else with the system opener
+func Rules(configRules []config.OpenerRule) []config.OpenerRule {
+	systemOpener := "xdg-open"
+	if runtime.GOOS == "darwin" {
+		systemOpener = "open"
+	}
+	isText := true
+	return append(slices.Clone(configRules),
+		config.OpenerRule{Name: "editor", Text: &isText},
+		config.OpenerRule{Name: systemOpener, Command: systemOpener + " %f", Mode: ModeDetached},
+		config.OpenerRule{Name: "editor"},
+	)
+}
+
+// Match returns all rules that apply to the file, in order of priority
+func Match(rules []config.OpenerRule, file File) []config.OpenerRule {
+	matches := []config.OpenerRule{}
+	for _, rule := range rules {
+		if Matches(rule, file) {
+			matches = append(matches, rule)
+		}
+	}
+	return matches
+}
+
+// Matches checks every condition the rule sets
+func Matches(rule config.OpenerRule, file File) bool {
+	base := filepath.Base(file.Path)
+	if len(rule.Ext) > 0 {
+		ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(base), "."))
+		if !containsFold(rule.Ext, ext) {
+			return false
+		}
+	}
+	if len(rule.Mime) > 0 && !matchesAny(rule.Mime, file.Mime) {
+		return false
+	}
+	if len(rule.Glob) > 0 {
+		matched := false
+		for _, glob := range rule.Glob {
+			name := base
+			if strings.Contains(glob, "/") {
+				name = file.Path
+			}
+			if ok, _ := filepath.Match(glob, name); ok {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if rule.Terminal != nil && *rule.Terminal != file.IsTerminal {
+		return false
+	}
+	if rule.Text != nil && *rule.Text != file.IsText {
+		return false
+	}
+	return true
+}
+
+// IsEditor reports whether a rule opens the file in the configured editor
+func IsEditor(rule config.OpenerRule) bool {
+	return rule.Command == ""
+}
+
+// Open runs the command of a rule for the file. Foreground commands suspend
+// the application until they exit.
+func Open(rule config.OpenerRule, path string, app *tview.Application) error {
+	command := shell.Expand(rule.Command, shell.Macros{File: path, Dir: filepath.Dir(path)})
+	cmd := shell.Command(command, filepath.Dir(path))
+	switch rule.Mode {
+	case "", ModeForeground:
+		return shell.RunSuspended(app, cmd, false)
+	case ModeBackground:
+		if err := cmd.Start(); err != nil {
+			return err
+		}
+		go cmd.Wait()
+		return nil
+	case ModeDetached:
+		return shell.RunDetached(cmd)
+	}
+	return fmt.Errorf("unknown opener mode %q", rule.Mode)
+}
+
+// OpenFile opens a file with a rule and records it in the history
+func OpenFile(path string, rule config.OpenerRule, app *tview.Application, config *config.Config) error {
+	if IsEditor(rule) {
+		return helper.OpenInEditor(path, 0, 0, nil, app, config)
+	}
+	if err := Open(rule, path, app); err != nil {
+		return err
+	}
+	helper.AddToHistory(path, config)
+	return nil
+}
+
+// OpenDefault opens a file with the first matching rule. As a file chooser
+// the file is always handed to the caller.
+func OpenDefault(path string, chooseFilePath *string, app *tview.Application, config *config.Config) error {
+	if chooseFilePath != nil {
+		return helper.OpenInEditor(path, 0, 0, chooseFilePath, app, config)
+	}
+	rules := Match(Rules(config.Openers), NewFile(path))
+	return OpenFile(path, rules[0], app, config)
+}
+
+// Label describes a rule in the open with menu
+func Label(rule config.OpenerRule) string {
+	if IsEditor(rule) {
+		return "editor"
+	}
+	if rule.Name != "" {
+		return rule.Name + " (" + rule.Command + ")"
+	}
+	return rule.Command
+}
+
+func matchesAny(patterns []string, mimeType string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, mimeType); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(strings.TrimPrefix(v, "."), value) {
+			return true
+		}
+	}
+	return false
+}
+
+func isTerminal() bool {
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
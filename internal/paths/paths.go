@@ -0,0 +1,118 @@
+package paths
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// APP_NAME names the directories of this app below the base directories
+const APP_NAME = "gofileyourself"
+
+// LEGACY_CONFIG_FILE is the config location, relative to the home directory,
+// used before the XDG base directories
+const LEGACY_CONFIG_FILE = ".gofileyourself.yaml"
+
+// xdgDir returns the directory in the environment variable, or the fallback
+// below the home directory. Relative paths in the variable are ignored as
+// required by the XDG base directory specification.
+func xdgDir(variable string, fallback string) string {
+	if dir := os.Getenv(variable); filepath.IsAbs(dir) {
+		return dir
+	}
+	return filepath.Join(HomeDir(), fallback)
+}
+
+// HomeDir returns the home directory of the user
+func HomeDir() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return os.Getenv("HOME")
+	}
+	return homeDir
+}
+
+// ConfigHome returns $XDG_CONFIG_HOME, by default ~/.config
+func ConfigHome() string {
+	return xdgDir("XDG_CONFIG_HOME", ".config")
+}
+
+// DataHome returns $XDG_DATA_HOME, by default ~/.local/share
+func DataHome() string {
+	return xdgDir("XDG_DATA_HOME", filepath.Join(".local", "share"))
+}
+
+// StateHome returns $XDG_STATE_HOME, by default ~/.local/state
+func StateHome() string {
+	return xdgDir("XDG_STATE_HOME", filepath.Join(".local", "state"))
+}
+
+// CacheHome returns $XDG_CACHE_HOME, by default ~/.cache
+func CacheHome() string {
+	return xdgDir("XDG_CACHE_HOME", ".cache")
+}
+
+// RuntimeDir returns the directory of this app in $XDG_RUNTIME_DIR for
+// transient files. Without it a directory per user in the temporary directory
+// is used, as the specification suggests.
+func RuntimeDir() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); filepath.IsAbs(dir) {
+		return filepath.Join(dir, APP_NAME)
+	}
+	return filepath.Join(os.TempDir(), APP_NAME+"-"+strconv.Itoa(os.Getuid()))
+}
+
+// ConfigFile returns the default config file. The legacy ~/.gofileyourself.yaml
+// is still used as long as there is no config file in $XDG_CONFIG_HOME.
+func ConfigFile() string {
+	configFile := filepath.Join(ConfigHome(), APP_NAME, "config.yaml")
+	if exists(configFile) {
+		return configFile
+	}
+	if legacyFile := filepath.Join(HomeDir(), LEGACY_CONFIG_FILE); exists(legacyFile) {
+		return legacyFile
+	}
+	return configFile
+}
+
+// StateFile returns the location of a state file in $XDG_STATE_HOME
+func StateFile(name string) string {
+	return filepath.Join(StateHome(), APP_NAME, name)
+}
+
+// CacheDir returns the cache directory of this app in $XDG_CACHE_HOME
+func CacheDir() string {
+	return filepath.Join(CacheHome(), APP_NAME)
+}
+
+// Expand replaces a leading ~ with the home directory
+func Expand(path string) string {
+	if path == "~" {
+		return HomeDir()
+	}
+	if rest, found := strings.CutPrefix(path, "~/"); found {
+		return filepath.Join(HomeDir(), rest)
+	}
+	return path
+}
+
+// Migrate moves a legacy file from the home directory to its new location,
+// unless there already is a file at the new location
+func Migrate(legacyName string, target string) error {
+	legacyFile := filepath.Join(HomeDir(), legacyName)
+	if !exists(legacyFile) || exists(target) {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0o700); err != nil {
+		return err
+	}
+	return os.Rename(legacyFile, target)
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return !errors.Is(err, fs.ErrNotExist)
+}
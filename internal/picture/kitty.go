@@ -0,0 +1,90 @@
+package picture
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image/png"
+	"os"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// KITTY_CHUNK_SIZE is the largest piece of base64 data in one escape sequence
+// of the kitty graphics protocol
+const KITTY_CHUNK_SIZE = 4096
+
+// kittyPlacement is a picture placed on the cells of a rectangle
+type kittyPlacement struct {
+	picture *Picture
+	x       int
+	y       int
+	columns int
+	rows    int
+}
+
+// The picture placed while drawing and the one shown in the terminal. The
+// terminal only changes when they differ.
+var (
+	placedKitty *kittyPlacement
+	shownKitty  *kittyPlacement
+	tty         *os.File
+)
+
+// placeKitty shows a picture on cells once the screen is drawn
+func placeKitty(picture *Picture, x int, y int, columns int, rows int) {
+	placedKitty = &kittyPlacement{picture: picture, x: x, y: y, columns: columns, rows: rows}
+}
+
+// FlushKitty shows the picture placed while drawing with the kitty graphics
+// protocol, or removes the shown one. tcell does not know about the picture,
+// so it is written to the terminal after every draw of the application.
+func FlushKitty(screen tcell.Screen) {
+	placed := placedKitty
+	placedKitty = nil
+	if placed != nil && shownKitty != nil && *placed == *shownKitty {
+		return
+	}
+	if placed == nil && shownKitty == nil {
+		return
+	}
+	if tty == nil {
+		var err error
+		if tty, err = os.OpenFile("/dev/tty", os.O_WRONLY, 0); err != nil {
+			return
+		}
+	}
+	shownKitty = placed
+	var sequences bytes.Buffer
+	// Delete all pictures, q=2 suppresses the responses of the terminal
+	sequences.WriteString("\x1b_Ga=d,q=2\x1b\\")
+	if placed != nil {
+		writeKittyPicture(&sequences, placed)
+	}
+	tty.Write(sequences.Bytes())
+}
+
+// writeKittyPicture transmits a picture as PNG and places it at the cells of
+// the placement without moving the cursor
+func writeKittyPicture(sequences *bytes.Buffer, placement *kittyPlacement) {
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, placement.picture.Image); err != nil {
+		return
+	}
+	data := base64.StdEncoding.EncodeToString(encoded.Bytes())
+	// Save the cursor, move to the top left cell and restore the cursor
+	fmt.Fprintf(sequences, "\x1b7\x1b[%d;%dH", placement.y+1, placement.x+1)
+	for start := 0; start < len(data); start += KITTY_CHUNK_SIZE {
+		end := min(start+KITTY_CHUNK_SIZE, len(data))
+		more := 0
+		if end < len(data) {
+			more = 1
+		}
+		if start == 0 {
+			fmt.Fprintf(sequences, "\x1b_Ga=T,f=100,q=2,C=1,c=%d,r=%d,m=%d;%s\x1b\\", placement.columns, placement.rows, more, data[start:end])
+		} else {
+			fmt.Fprintf(sequences, "\x1b_Gm=%d;%s\x1b\\", more, data[start:end])
+		}
+	}
+	sequences.WriteString("\x1b8")
+}
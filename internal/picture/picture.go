@@ -0,0 +1,194 @@
+package picture
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+	"strings"
+
+	"github.com/thilobro/gofileyourself/internal/config"
+	"github.com/thilobro/gofileyourself/internal/helper"
+	"github.com/thilobro/gofileyourself/internal/theme"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+const (
+	// MAX_SIZE is the largest width and height in pixels of a decoded
+	// picture. Larger pictures are scaled down once when they are decoded.
+	MAX_SIZE = 320
+	// MAX_PIXELS limits the pictures that are decoded at all
+	MAX_PIXELS = 32 << 20
+)
+
+// upperHalfBlock is drawn with the upper pixel as foreground and the lower
+// one as background color
+const upperHalfBlock = '▀'
+
+// Picture is a decoded image, scaled down to at most MAX_SIZE
+type Picture struct {
+	Image *image.RGBA
+	// Format, Width and Height describe the file
+	Format string
+	Width  int
+	Height int
+	Size   int64
+}
+
+// Decode reads a PNG, JPEG or GIF file. Files in other formats have no
+// picture; animated GIFs show their first frame.
+func Decode(path string) (*Picture, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	imageConfig, format, err := image.DecodeConfig(file)
+	if errors.Is(err, image.ErrFormat) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	picture := &Picture{Format: format, Width: imageConfig.Width, Height: imageConfig.Height, Size: info.Size()}
+	if imageConfig.Width*imageConfig.Height > MAX_PIXELS {
+		return picture, nil
+	}
+	if _, err := file.Seek(0, 0); err != nil {
+		return nil, err
+	}
+	decoded, _, err := image.Decode(file)
+	if err != nil {
+		return nil, err
+	}
+	width, height := fit(imageConfig.Width, imageConfig.Height, MAX_SIZE, MAX_SIZE)
+	picture.Image = scale(decoded, width, height)
+	return picture, nil
+}
+
+// Info describes the format and the dimensions of the picture
+func (picture *Picture) Info() string {
+	info := fmt.Sprintf("%s, %d×%d, %s", strings.ToUpper(picture.Format), picture.Width, picture.Height, helper.FormatSize(picture.Size))
+	if picture.Image == nil {
+		info += ", too large to show"
+	}
+	return info
+}
+
+// fit returns the largest size within maxWidth and maxHeight with the
+// aspect ratio of width and height. Pictures are not enlarged.
+func fit(width int, height int, maxWidth int, maxHeight int) (int, int) {
+	if width <= maxWidth && height <= maxHeight {
+		return width, height
+	}
+	if width*maxHeight > height*maxWidth {
+		return maxWidth, max(height*maxWidth/width, 1)
+	}
+	return max(width*maxHeight/height, 1), maxHeight
+}
+
+// scale resizes an image by averaging the pixels that fall on each pixel of
+// the result
+func scale(source image.Image, width int, height int) *image.RGBA {
+	bounds := source.Bounds()
+	scaled := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0 := bounds.Min.Y + y*bounds.Dy()/height
+		y1 := max(bounds.Min.Y+(y+1)*bounds.Dy()/height, y0+1)
+		for x := 0; x < width; x++ {
+			x0 := bounds.Min.X + x*bounds.Dx()/width
+			x1 := max(bounds.Min.X+(x+1)*bounds.Dx()/width, x0+1)
+			var r, g, b, a, count uint32
+			for sourceY := y0; sourceY < y1; sourceY++ {
+				for sourceX := x0; sourceX < x1; sourceX++ {
+					pixelR, pixelG, pixelB, pixelA := source.At(sourceX, sourceY).RGBA()
+					r, g, b, a = r+pixelR, g+pixelG, b+pixelB, a+pixelA
+					count++
+				}
+			}
+			scaled.SetRGBA(x, y, color.RGBA{
+				R: uint8(r / count >> 8),
+				G: uint8(g / count >> 8),
+				B: uint8(b / count >> 8),
+				A: uint8(a / count >> 8),
+			})
+		}
+	}
+	return scaled
+}
+
+// View draws a picture below a line with its format and dimensions, scaled
+// to the size of the view. protocol is a value of preview.image_protocol.
+type View struct {
+	*tview.Box
+	picture  *Picture
+	protocol string
+	// scaled is the picture at the size it was last drawn with
+	scaled *image.RGBA
+}
+
+func NewView(picture *Picture, protocol string) *View {
+	return &View{
+		Box:      tview.NewBox(),
+		picture:  picture,
+		protocol: protocol,
+	}
+}
+
+func (view *View) Draw(screen tcell.Screen) {
+	view.Box.DrawForSubclass(screen, view)
+	x, y, width, height := view.GetInnerRect()
+	tview.Print(screen, tview.Escape(view.picture.Info()), x, y, width, tview.AlignLeft, theme.Current().Muted)
+	// Without colors the picture would be a pattern of blocks
+	if view.picture.Image == nil || theme.ColorDepth() == theme.MONOCHROME || height < 3 {
+		return
+	}
+	y, height = y+2, height-2
+
+	// A cell shows two pixels on top of each other
+	bounds := view.picture.Image.Bounds()
+	columns, pixelRows := fit(bounds.Dx(), bounds.Dy(), width, 2*height)
+	if view.protocol == config.ImageKitty {
+		placeKitty(view.picture, x, y, columns, (pixelRows+1)/2)
+		return
+	}
+	if view.scaled == nil || view.scaled.Bounds().Dx() != columns || view.scaled.Bounds().Dy() != pixelRows {
+		view.scaled = scale(view.picture.Image, columns, pixelRows)
+	}
+	background := view.GetBackgroundColor()
+	for row := 0; row < (pixelRows+1)/2; row++ {
+		for column := 0; column < columns; column++ {
+			upper := pixelColor(view.scaled, column, 2*row, background)
+			lower := pixelColor(view.scaled, column, 2*row+1, background)
+			style := tcell.StyleDefault.Foreground(upper).Background(lower)
+			screen.SetContent(x+column, y+row, upperHalfBlock, nil, style)
+		}
+	}
+}
+
+// pixelColor returns the quantized color of a pixel blended over the
+// background, the background below the picture
+func pixelColor(picture *image.RGBA, x int, y int, background tcell.Color) tcell.Color {
+	if y >= picture.Bounds().Dy() {
+		return background
+	}
+	pixel := picture.RGBAAt(x, y)
+	if pixel.A < 0xff && background.Valid() {
+		// The pixel is premultiplied with its alpha
+		backgroundR, backgroundG, backgroundB := background.RGB()
+		transparency := int32(0xff - pixel.A)
+		pixel.R += uint8(backgroundR * transparency / 0xff)
+		pixel.G += uint8(backgroundG * transparency / 0xff)
+		pixel.B += uint8(backgroundB * transparency / 0xff)
+	}
+	return theme.Quantize(tcell.NewRGBColor(int32(pixel.R), int32(pixel.G), int32(pixel.B)))
+}
@@ -0,0 +1,223 @@
+package preview
+
+import (
+	"container/list"
+	"context"
+	"os"
+	"sync"
+	"time"
+)
+
+// Key identifies a version of a file. A changed file gets a new key.
+type Key struct {
+	Path    string
+	ModTime time.Time
+	Size    int64
+}
+
+// NewKey returns the key of the current version of a file
+func NewKey(path string) (Key, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return Key{}, err
+	}
+	return Key{Path: path, ModTime: info.ModTime(), Size: info.Size()}, nil
+}
+
+// Cache keeps the values of the most recently used keys
+type Cache[T any] struct {
+	capacity int
+	// order holds the items from the most to the least recently used one
+	order *list.List
+	items map[Key]*list.Element
+	// generation counts the calls of Clear
+	generation int
+	mutex      sync.Mutex
+}
+
+type cacheItem[T any] struct {
+	key   Key
+	value T
+}
+
+func NewCache[T any](capacity int) *Cache[T] {
+	return &Cache[T]{
+		capacity: capacity,
+		order:    list.New(),
+		items:    map[Key]*list.Element{},
+	}
+}
+
+// Get returns the value of a key and marks it as recently used
+func (cache *Cache[T]) Get(key Key) (T, bool) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	element, found := cache.items[key]
+	if !found {
+		var zero T
+		return zero, false
+	}
+	cache.order.MoveToFront(element)
+	return element.Value.(*cacheItem[T]).value, true
+}
+
+// Add stores the value of a key and drops the least recently used value once
+// the cache is full
+func (cache *Cache[T]) Add(key Key, value T) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	cache.add(key, value)
+}
+
+// AddIfCurrent stores the value of a key unless the cache was cleared since
+// Generation returned generation, e.g. while the value was generated with
+// options that are outdated now
+func (cache *Cache[T]) AddIfCurrent(key Key, value T, generation int) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	if generation == cache.generation {
+		cache.add(key, value)
+	}
+}
+
+// Generation returns the number of times the cache was cleared
+func (cache *Cache[T]) Generation() int {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	return cache.generation
+}
+
+func (cache *Cache[T]) add(key Key, value T) {
+	if element, found := cache.items[key]; found {
+		element.Value.(*cacheItem[T]).value = value
+		cache.order.MoveToFront(element)
+		return
+	}
+	cache.items[key] = cache.order.PushFront(&cacheItem[T]{key: key, value: value})
+	if cache.order.Len() > cache.capacity {
+		oldest := cache.order.Back()
+		cache.order.Remove(oldest)
+		delete(cache.items, oldest.Value.(*cacheItem[T]).key)
+	}
+}
+
+// Clear removes all values, e.g. after the options they depend on changed
+func (cache *Cache[T]) Clear() {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	cache.order.Init()
+	clear(cache.items)
+	cache.generation++
+}
+
+// Loader generates previews in a worker goroutine and caches them. Only the
+// latest request matters: a new one cancels the previous one, and results
+// of cancelled requests are dropped.
+type Loader[T any] struct {
+	cache *Cache[T]
+	// queue runs a function on the goroutine of the user interface
+	queue   func(func())
+	pending *request[T]
+	cancel  context.CancelFunc
+	// isRunning is set while the worker goroutine runs. It stops once there
+	// are no requests.
+	isRunning bool
+	mutex     sync.Mutex
+}
+
+type request[T any] struct {
+	context context.Context
+	path    string
+	load    func(context.Context, string) (T, error)
+	deliver func(T, error)
+}
+
+// NewLoader creates a loader that caches capacity previews. queue is used to
+// deliver the results, e.g. Application.QueueUpdateDraw.
+func NewLoader[T any](capacity int, queue func(func())) *Loader[T] {
+	return &Loader[T]{
+		cache: NewCache[T](capacity),
+		queue: queue,
+	}
+}
+
+// Load requests the preview of a file. load generates it unless it is cached
+// for the version of the file, deliver receives it on the goroutine of queue.
+// load should give up once its context is cancelled.
+func (loader *Loader[T]) Load(path string, load func(context.Context, string) (T, error), deliver func(T, error)) {
+	loader.Cancel()
+	loader.mutex.Lock()
+	defer loader.mutex.Unlock()
+	requestContext, cancel := context.WithCancel(context.Background())
+	loader.cancel = cancel
+	loader.pending = &request[T]{
+		context: requestContext,
+		path:    path,
+		load:    load,
+		deliver: deliver,
+	}
+	if !loader.isRunning {
+		loader.isRunning = true
+		go loader.work()
+	}
+}
+
+// Cancel drops the current request
+func (loader *Loader[T]) Cancel() {
+	loader.mutex.Lock()
+	defer loader.mutex.Unlock()
+	if loader.cancel != nil {
+		loader.cancel()
+		loader.cancel = nil
+	}
+	loader.pending = nil
+}
+
+// Clear removes all cached previews. Previews that are being generated are
+// not cached.
+func (loader *Loader[T]) Clear() {
+	loader.cache.Clear()
+}
+
+func (loader *Loader[T]) work() {
+	for {
+		loader.mutex.Lock()
+		request := loader.pending
+		loader.pending = nil
+		if request == nil {
+			loader.isRunning = false
+			loader.mutex.Unlock()
+			return
+		}
+		loader.mutex.Unlock()
+
+		value, err := loader.generate(request)
+		if request.context.Err() != nil {
+			continue
+		}
+		loader.queue(func() {
+			// The request may have been cancelled while the result waited
+			if request.context.Err() == nil {
+				request.deliver(value, err)
+			}
+		})
+	}
+}
+
+// generate returns the cached preview of the request or loads it
+func (loader *Loader[T]) generate(request *request[T]) (T, error) {
+	key, err := NewKey(request.path)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	if value, found := loader.cache.Get(key); found {
+		return value, nil
+	}
+	generation := loader.cache.Generation()
+	value, err := request.load(request.context, request.path)
+	if err == nil && request.context.Err() == nil {
+		loader.cache.AddIfCurrent(key, value, generation)
+	}
+	return value, err
+}
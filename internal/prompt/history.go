@@ -0,0 +1,66 @@
+package prompt
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// History stores the entered command lines, oldest first, in a file so that
+// they survive restarts
+type History struct {
+	path    string
+	maxLen  int
+	entries []string
+}
+
+// LoadHistory reads the history file, a missing file is an empty history
+func LoadHistory(path string, maxLen int) *History {
+	history := &History{path: path, maxLen: maxLen}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return history
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		if line != "" {
+			history.entries = append(history.entries, line)
+		}
+	}
+	history.trim()
+	return history
+}
+
+// Add appends an entry, moving an earlier identical entry to the end, and
+// writes the history file
+func (history *History) Add(entry string) error {
+	if strings.Contains(entry, "\n") {
+		return nil
+	}
+	history.entries = slices.DeleteFunc(history.entries, func(existing string) bool {
+		return existing == entry
+	})
+	history.entries = append(history.entries, entry)
+	history.trim()
+	if err := os.MkdirAll(filepath.Dir(history.path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(history.path, []byte(strings.Join(history.entries, "\n")+"\n"), 0o600)
+}
+
+// Matching returns the entries that start with prefix, newest first
+func (history *History) Matching(prefix string) []string {
+	matches := []string{}
+	for i := len(history.entries) - 1; i >= 0; i-- {
+		if strings.HasPrefix(history.entries[i], prefix) {
+			matches = append(matches, history.entries[i])
+		}
+	}
+	return matches
+}
+
+func (history *History) trim() {
+	if history.maxLen > 0 && len(history.entries) > history.maxLen {
+		history.entries = history.entries[len(history.entries)-history.maxLen:]
+	}
+}
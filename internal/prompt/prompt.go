@@ -0,0 +1,111 @@
+package prompt
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// Completer returns the complete lines that the given text can be expanded to
+type Completer func(text string) []string
+
+// Prompt is a single line editor with tab completion and a history. The
+// prompt character is shown as the label and is not part of the text.
+type Prompt struct {
+	*tview.InputField
+	label          string
+	history        *History
+	completer      Completer
+	candidates     []string
+	candidateIndex int
+	historyIndex   int
+	historyPrefix  string
+}
+
+func New(label string, history *History, completer Completer) *Prompt {
+	prompt := &Prompt{
+		InputField:   tview.NewInputField().SetLabel(label),
+		label:        label,
+		history:      history,
+		completer:    completer,
+		historyIndex: -1,
+	}
+	prompt.SetInputCapture(prompt.inputCapture)
+	return prompt
+}
+
+// Line returns the prompt character followed by the text
+func (prompt *Prompt) Line() string {
+	return prompt.label + prompt.GetText()
+}
+
+func (prompt *Prompt) inputCapture(event *tcell.EventKey) *tcell.EventKey {
+	switch event.Key() {
+	case tcell.KeyTab:
+		prompt.complete(1)
+		return nil
+	case tcell.KeyBacktab:
+		prompt.complete(-1)
+		return nil
+	case tcell.KeyUp:
+		prompt.recall(1)
+		return nil
+	case tcell.KeyDown:
+		prompt.recall(-1)
+		return nil
+	case tcell.KeyBackspace, tcell.KeyBackspace2:
+		// Keep the prompt open when there is nothing left to delete
+		if prompt.GetText() == "" {
+			return nil
+		}
+	case tcell.KeyEnter:
+		if prompt.history != nil && prompt.GetText() != "" {
+			prompt.history.Add(prompt.Line())
+		}
+	}
+	prompt.candidates = nil
+	prompt.historyIndex = -1
+	return event
+}
+
+// complete replaces the text with the next completion candidate. Repeated
+// presses cycle through all candidates.
+func (prompt *Prompt) complete(step int) {
+	if prompt.completer == nil {
+		return
+	}
+	if prompt.candidates == nil {
+		prompt.candidates = prompt.completer(prompt.GetText())
+		prompt.candidateIndex = -1
+		if step < 0 {
+			prompt.candidateIndex = 0
+		}
+	}
+	if len(prompt.candidates) == 0 {
+		return
+	}
+	prompt.candidateIndex = (prompt.candidateIndex + step + len(prompt.candidates)) % len(prompt.candidates)
+	prompt.SetText(prompt.candidates[prompt.candidateIndex])
+}
+
+// recall walks through the history entries of this prompt that start with
+// the text typed before the first recall
+func (prompt *Prompt) recall(step int) {
+	if prompt.history == nil {
+		return
+	}
+	if prompt.historyIndex == -1 {
+		prompt.historyPrefix = prompt.GetText()
+	}
+	entries := prompt.history.Matching(prompt.label + prompt.historyPrefix)
+	index := prompt.historyIndex + step
+	if index < 0 {
+		prompt.historyIndex = -1
+		prompt.SetText(prompt.historyPrefix)
+		return
+	}
+	if index >= len(entries) {
+		return
+	}
+	prompt.historyIndex = index
+	prompt.SetText(entries[index][len(prompt.label):])
+}
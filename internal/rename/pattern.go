@@ -0,0 +1,187 @@
+package rename
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+const substitutionDelimiters = "/|#,:@!"
+
+var placeholderRegexp = regexp.MustCompile(`\{(\w+)(?::([^}]*))?\}`)
+
+// counterWidthRegexp matches the width of {n:03}, optionally zero padded
+var counterWidthRegexp = regexp.MustCompile(`^[0-9]+$`)
+
+// Pattern derives new file names either from a sed like substitution
+// s/regex/replacement/flags or from a template such as {name}_{n:03}.{ext}
+type Pattern struct {
+	regex       *regexp.Regexp
+	replacement string
+	global      bool
+	template    string
+}
+
+// ParsePattern parses a substitution or a template expression
+func ParsePattern(expression string) (*Pattern, error) {
+	if expression == "" {
+		return nil, errors.New("empty pattern")
+	}
+	if len(expression) > 2 && expression[0] == 's' && strings.ContainsRune(substitutionDelimiters, rune(expression[1])) {
+		return parseSubstitution(expression)
+	}
+	for _, match := range placeholderRegexp.FindAllStringSubmatch(expression, -1) {
+		if err := checkPlaceholder(match[1], match[2]); err != nil {
+			return nil, err
+		}
+	}
+	return &Pattern{template: expression}, nil
+}
+
+func parseSubstitution(expression string) (*Pattern, error) {
+	delimiter := string(expression[1])
+	parts := strings.Split(expression[2:], delimiter)
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("expected s%sregex%sreplacement%sflags", delimiter, delimiter, delimiter)
+	}
+	flags := parts[2]
+	regex := parts[0]
+	if strings.Contains(flags, "i") {
+		regex = "(?i)" + regex
+	}
+	compiled, err := regexp.Compile(regex)
+	if err != nil {
+		return nil, err
+	}
+	return &Pattern{
+		regex:       compiled,
+		replacement: parts[1],
+		global:      strings.Contains(flags, "g"),
+	}, nil
+}
+
+// Targets returns the new base name for each of the given paths
+func (pattern *Pattern) Targets(paths []string) ([]string, error) {
+	targets := make([]string, len(paths))
+	for i, path := range paths {
+		if pattern.regex != nil {
+			targets[i] = pattern.substitute(filepath.Base(path))
+			continue
+		}
+		target, err := pattern.expand(path, i+1)
+		if err != nil {
+			return nil, err
+		}
+		targets[i] = target
+	}
+	return targets, nil
+}
+
+func (pattern *Pattern) substitute(name string) string {
+	if pattern.global {
+		return pattern.regex.ReplaceAllString(name, pattern.replacement)
+	}
+	match := pattern.regex.FindStringSubmatchIndex(name)
+	if match == nil {
+		return name
+	}
+	result := pattern.regex.ExpandString(nil, pattern.replacement, name, match)
+	return name[:match[0]] + string(result) + name[match[1]:]
+}
+
+func (pattern *Pattern) expand(path string, index int) (string, error) {
+	var result strings.Builder
+	end := 0
+	for _, match := range placeholderRegexp.FindAllStringSubmatchIndex(pattern.template, -1) {
+		literal := pattern.template[end:match[0]]
+		key := pattern.template[match[2]:match[3]]
+		modifier := ""
+		if match[4] >= 0 {
+			modifier = pattern.template[match[4]:match[5]]
+		}
+		value, err := placeholderValue(path, index, key, modifier)
+		if err != nil {
+			return "", err
+		}
+		// Files without extension get no trailing dot from {name}.{ext}
+		if key == "ext" && value == "" {
+			literal = strings.TrimSuffix(literal, ".")
+		}
+		result.WriteString(literal)
+		result.WriteString(value)
+		end = match[1]
+	}
+	result.WriteString(pattern.template[end:])
+	return result.String(), nil
+}
+
+func checkPlaceholder(key string, modifier string) error {
+	switch key {
+	case "name", "ext", "base", "parent":
+		if modifier != "" && modifier != "upper" && modifier != "lower" && modifier != "title" {
+			return fmt.Errorf("unknown case transform %q", modifier)
+		}
+	case "n":
+		if modifier != "" && !counterWidthRegexp.MatchString(modifier) {
+			return fmt.Errorf("invalid counter width %q", modifier)
+		}
+	case "mtime":
+	default:
+		return fmt.Errorf("unknown placeholder {%s}", key)
+	}
+	return nil
+}
+
+func placeholderValue(path string, index int, key string, modifier string) (string, error) {
+	base := filepath.Base(path)
+	ext := filepath.Ext(base)
+	switch key {
+	case "name":
+		return transformCase(strings.TrimSuffix(base, ext), modifier), nil
+	case "ext":
+		return transformCase(strings.TrimPrefix(ext, "."), modifier), nil
+	case "base":
+		return transformCase(base, modifier), nil
+	case "parent":
+		return transformCase(filepath.Base(filepath.Dir(path)), modifier), nil
+	case "n":
+		return fmt.Sprintf("%"+modifier+"d", index), nil
+	case "mtime":
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", err
+		}
+		layout := modifier
+		if layout == "" {
+			layout = "2006-01-02"
+		}
+		return info.ModTime().Format(layout), nil
+	}
+	return "", fmt.Errorf("unknown placeholder {%s}", key)
+}
+
+func transformCase(value string, transform string) string {
+	switch transform {
+	case "upper":
+		return strings.ToUpper(value)
+	case "lower":
+		return strings.ToLower(value)
+	case "title":
+		runes := []rune(strings.ToLower(value))
+		for i := range runes {
+			if i == 0 || !isWordCharacter(runes[i-1]) {
+				runes[i] = unicode.ToUpper(runes[i])
+			}
+		}
+		return string(runes)
+	}
+	return value
+}
+
+func isWordCharacter(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,233 @@
+package rename
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/thilobro/gofileyourself/internal/helper"
+	"github.com/thilobro/gofileyourself/internal/theme"
+
+	"github.com/rivo/tview"
+)
+
+// Operation moves a single file from one path to another
+type Operation struct {
+	From string
+	To   string
+}
+
+// Plan is a validated set of renames together with the order of steps that
+// applies them without overwriting any file
+type Plan struct {
+	Renames []Operation
+	Steps   []Operation
+	Mkdirs  []string
+}
+
+// NewPlan matches every source to the target at the same position. Relative
+// targets are resolved against the directory of their source.
+func NewPlan(sources []string, targets []string) (*Plan, error) {
+	if len(sources) != len(targets) {
+		return nil, fmt.Errorf("expected %d names, got %d", len(sources), len(targets))
+	}
+
+	plan := &Plan{}
+	seenTargets := make(map[string]string)
+	for i, source := range sources {
+		target := strings.TrimSpace(targets[i])
+		if target == "" {
+			return nil, fmt.Errorf("line %d: empty name for %s", i+1, filepath.Base(source))
+		}
+		target = filepath.Clean(helper.GetAbsFilePath(target, filepath.Dir(source)))
+		if otherSource, exists := seenTargets[target]; exists {
+			return nil, fmt.Errorf("line %d: %s is also the target of %s", i+1, target, filepath.Base(otherSource))
+		}
+		seenTargets[target] = source
+		if target != source {
+			plan.Renames = append(plan.Renames, Operation{From: source, To: target})
+		}
+	}
+
+	if err := plan.checkNesting(); err != nil {
+		return nil, err
+	}
+	if err := plan.checkCollisions(); err != nil {
+		return nil, err
+	}
+	plan.collectDirectories()
+	if err := plan.orderSteps(); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// checkNesting rejects renaming a file together with a directory containing
+// it, since the file is no longer at its path once the directory moved
+func (plan *Plan) checkNesting() error {
+	for _, rename := range plan.Renames {
+		for _, other := range plan.Renames {
+			if strings.HasPrefix(rename.From, other.From+string(filepath.Separator)) {
+				return fmt.Errorf("cannot rename %s together with %s, which contains it", rename.From, other.From)
+			}
+		}
+	}
+	return nil
+}
+
+// checkCollisions rejects targets that exist and are not moved away by the plan
+func (plan *Plan) checkCollisions() error {
+	movedAway := make(map[string]bool)
+	for _, rename := range plan.Renames {
+		movedAway[rename.From] = true
+	}
+	for _, rename := range plan.Renames {
+		if _, err := os.Lstat(rename.To); err == nil && !movedAway[rename.To] {
+			return fmt.Errorf("%s already exists", rename.To)
+		}
+		if strings.HasPrefix(rename.To, rename.From+string(filepath.Separator)) {
+			return fmt.Errorf("cannot move %s into itself", rename.From)
+		}
+	}
+	return nil
+}
+
+// collectDirectories finds the target directories that have to be created,
+// including their missing parents. Parents are sorted before the directories
+// in them.
+func (plan *Plan) collectDirectories() {
+	seen := make(map[string]bool)
+	for _, rename := range plan.Renames {
+		for dir := filepath.Dir(rename.To); !seen[dir]; dir = filepath.Dir(dir) {
+			if _, err := os.Stat(dir); err == nil {
+				break
+			}
+			seen[dir] = true
+			plan.Mkdirs = append(plan.Mkdirs, dir)
+		}
+	}
+	sort.Strings(plan.Mkdirs)
+}
+
+// orderSteps sorts the renames so that no target is written before its
+// current owner moved away. Cycles are broken with a temporary name.
+func (plan *Plan) orderSteps() error {
+	pending := make(map[string]string)
+	for _, rename := range plan.Renames {
+		pending[rename.From] = rename.To
+	}
+	order := make([]string, 0, len(plan.Renames))
+	for _, rename := range plan.Renames {
+		order = append(order, rename.From)
+	}
+
+	for len(pending) > 0 {
+		progress := false
+		for _, from := range order {
+			to, exists := pending[from]
+			if !exists {
+				continue
+			}
+			if _, blocked := pending[to]; blocked {
+				continue
+			}
+			plan.Steps = append(plan.Steps, Operation{From: from, To: to})
+			delete(pending, from)
+			progress = true
+		}
+		if progress {
+			continue
+		}
+
+		// Only cycles are left, move one member out of the way
+		for _, from := range order {
+			to, exists := pending[from]
+			if !exists {
+				continue
+			}
+			tempPath, err := temporaryName(from)
+			if err != nil {
+				return err
+			}
+			plan.Steps = append(plan.Steps, Operation{From: from, To: tempPath})
+			delete(pending, from)
+			pending[tempPath] = to
+			order = append(order, tempPath)
+			break
+		}
+	}
+	return nil
+}
+
+func temporaryName(path string) (string, error) {
+	dir, base := filepath.Split(path)
+	for i := 0; i < 1000; i++ {
+		tempPath := filepath.Join(dir, ".gofileyourself-rename-"+strconv.Itoa(i)+"-"+base)
+		if _, err := os.Lstat(tempPath); os.IsNotExist(err) {
+			return tempPath, nil
+		}
+	}
+	return "", fmt.Errorf("no temporary name available for %s", path)
+}
+
+// Preview returns a colored diff of the plan to be shown before applying it
+func (plan *Plan) Preview(baseDir string) []string {
+	relative := func(path string) string {
+		if relPath, err := filepath.Rel(baseDir, path); err == nil {
+			return tview.Escape(relPath)
+		}
+		return tview.Escape(path)
+	}
+	lines := []string{}
+	for _, dir := range plan.Mkdirs {
+		lines = append(lines, theme.Tag(theme.Current().Success, "")+"+ mkdir "+relative(dir)+"/[-::]")
+	}
+	for _, rename := range plan.Renames {
+		lines = append(lines, theme.Tag(theme.Current().Error, "")+"- "+relative(rename.From)+"[-::]")
+		lines = append(lines, theme.Tag(theme.Current().Success, "")+"+ "+relative(rename.To)+"[-::]")
+	}
+	if len(lines) == 0 {
+		lines = append(lines, theme.Tag(theme.Current().Muted, "")+"Nothing to rename...[-::]")
+	}
+	return lines
+}
+
+// Apply creates missing directories and runs the rename steps in order. If
+// a step fails, the steps before it are undone in reverse order, so that no
+// file is left under a temporary name; files that cannot be moved back are
+// listed in the error.
+func (plan *Plan) Apply() error {
+	for _, dir := range plan.Mkdirs {
+		if err := helper.CreateDirectory(dir); err != nil {
+			return err
+		}
+	}
+	for i, step := range plan.Steps {
+		if err := helper.RenameFile(step.From, step.To); err != nil {
+			return plan.undo(i, err)
+		}
+	}
+	return nil
+}
+
+// undo moves the files of the first done steps back after err and removes
+// the directories created for them, if they are empty
+func (plan *Plan) undo(done int, err error) error {
+	stranded := []string{}
+	for i := done - 1; i >= 0; i-- {
+		step := plan.Steps[i]
+		if undoErr := helper.RenameFile(step.To, step.From); undoErr != nil {
+			stranded = append(stranded, step.From+" is at "+step.To)
+		}
+	}
+	for i := len(plan.Mkdirs) - 1; i >= 0; i-- {
+		os.Remove(plan.Mkdirs[i])
+	}
+	if len(stranded) > 0 {
+		return fmt.Errorf("%w, could not undo the other renames: %s", err, strings.Join(stranded, ", "))
+	}
+	return fmt.Errorf("%w, the other renames were undone", err)
+}
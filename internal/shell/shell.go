@@ -0,0 +1,164 @@
+package shell
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/rivo/tview"
+	"golang.org/x/term"
+)
+
+// Macros are the values that are substituted into command templates
+type Macros struct {
+	File      string
+	Dir       string
+	Selection []string
+	Yanked    []string
+}
+
+// Options control how a shell command interacts with the user interface
+type Options struct {
+	// Wait for a key press after the command exited
+	Wait bool
+	// Capture the output instead of suspending the user interface
+	Capture bool
+	// Detach starts the command in its own session without waiting for it
+	Detach bool
+}
+
+// Quote quotes a string for use as a single word in a POSIX shell
+func Quote(value string) string {
+	if value == "" {
+		return "''"
+	}
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+func quoteAll(values []string) string {
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = Quote(value)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// Expand replaces the macros %f (current file), %d (current directory),
+// %s (selected files) and %c (yanked files) with their shell quoted values.
+// %% results in a literal percent sign.
+func Expand(template string, macros Macros) string {
+	var builder strings.Builder
+	for i := 0; i < len(template); i++ {
+		if template[i] != '%' || i+1 == len(template) {
+			builder.WriteByte(template[i])
+			continue
+		}
+		i++
+		switch template[i] {
+		case 'f':
+			builder.WriteString(Quote(macros.File))
+		case 'd':
+			builder.WriteString(Quote(macros.Dir))
+		case 's':
+			builder.WriteString(quoteAll(macros.Selection))
+		case 'c':
+			builder.WriteString(quoteAll(macros.Yanked))
+		case '%':
+			builder.WriteByte('%')
+		default:
+			builder.WriteByte('%')
+			builder.WriteByte(template[i])
+		}
+	}
+	return builder.String()
+}
+
+// ParseFlags splits leading flags off a command: -w waits for a key press,
+// -p captures the output and -f detaches the command
+func ParseFlags(command string) (Options, string) {
+	options := Options{}
+	for {
+		command = strings.TrimLeft(command, " ")
+		flag, rest, _ := strings.Cut(command, " ")
+		if len(flag) < 2 || flag[0] != '-' || strings.Trim(flag[1:], "wpf") != "" {
+			return options, command
+		}
+		options.Wait = options.Wait || strings.Contains(flag, "w")
+		options.Capture = options.Capture || strings.Contains(flag, "p")
+		options.Detach = options.Detach || strings.Contains(flag, "f")
+		command = rest
+	}
+}
+
+// Command returns a command that runs a command line with sh in dir
+func Command(command string, dir string) *exec.Cmd {
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Dir = dir
+	return cmd
+}
+
+// CommandContext returns a command like Command that is killed together with
+// the processes it started once ctx is cancelled
+func CommandContext(ctx context.Context, command string, dir string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Dir = dir
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	// Processes that left the group may keep the output open
+	cmd.WaitDelay = time.Second
+	return cmd
+}
+
+// RunSuspended suspends the application and runs the command attached to the
+// terminal, optionally waiting for a key press afterwards
+func RunSuspended(app *tview.Application, cmd *exec.Cmd, wait bool) error {
+	var err error
+	app.Suspend(func() {
+		cmd.Stdin = os.Stdin
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		err = cmd.Run()
+		if wait {
+			if err != nil {
+				fmt.Fprintln(os.Stdout, err)
+			}
+			fmt.Fprint(os.Stdout, "\nPress any key to continue")
+			WaitForKey()
+		}
+	})
+	return err
+}
+
+// RunDetached starts the command in a new session and does not wait for it.
+// It is reaped in the background once it exits.
+func RunDetached(cmd *exec.Cmd) error {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go cmd.Wait()
+	return nil
+}
+
+// RunCaptured runs the command and writes its output to writer
+func RunCaptured(cmd *exec.Cmd, writer io.Writer) error {
+	cmd.Stdout = writer
+	cmd.Stderr = writer
+	return cmd.Run()
+}
+
+// WaitForKey reads a single key press from the terminal
+func WaitForKey() {
+	fd := int(os.Stdin.Fd())
+	if state, err := term.MakeRaw(fd); err == nil {
+		defer term.Restore(fd, state)
+	}
+	os.Stdin.Read(make([]byte, 1))
+}
@@ -0,0 +1,168 @@
+package theme
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"sync"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Color depths, the number of colors a terminal can show
+const (
+	MONOCHROME   = 0
+	ANSI_COLORS  = 16
+	XTERM_COLORS = 256
+	TRUE_COLORS  = 1 << 24
+)
+
+// Modes of the --color flag
+const (
+	COLOR_AUTO   = "auto"
+	COLOR_NEVER  = "never"
+	COLOR_ALWAYS = "always"
+)
+
+// ansiColorNames are the tview names of the 16 standard terminal colors
+var ansiColorNames = []string{
+	"black", "maroon", "green", "olive", "navy", "purple", "teal", "silver",
+	"gray", "red", "lime", "yellow", "blue", "fuchsia", "aqua", "white",
+}
+
+var colorDepth = TRUE_COLORS
+
+// nearestColors caches the colors found by Quantize for the color depth
+var (
+	nearestColors      = map[tcell.Color]tcell.Color{}
+	nearestColorsMutex sync.Mutex
+)
+
+// source is the active theme before its colors were quantized
+var source *Theme
+
+func init() {
+	// tview tags only know color names and RGB values. Naming the xterm
+	// palette lets tags use it instead of RGB colors the terminal may not
+	// support.
+	for index := len(ansiColorNames); index < XTERM_COLORS; index++ {
+		tcell.ColorNames["color"+strconv.Itoa(index)] = tcell.PaletteColor(index)
+	}
+}
+
+// ParseColorMode returns the color depth for a --color mode: auto uses the
+// colors of the terminal unless $NO_COLOR is set, always uses true colors,
+// never none; 16 and 256 limit the colors to a palette
+func ParseColorMode(mode string, terminalColors int) (int, error) {
+	switch mode {
+	case COLOR_AUTO:
+		if os.Getenv("NO_COLOR") != "" {
+			return MONOCHROME, nil
+		}
+		return terminalColors, nil
+	case COLOR_ALWAYS:
+		return TRUE_COLORS, nil
+	case COLOR_NEVER:
+		return MONOCHROME, nil
+	case strconv.Itoa(ANSI_COLORS):
+		return ANSI_COLORS, nil
+	case strconv.Itoa(XTERM_COLORS):
+		return XTERM_COLORS, nil
+	}
+	return 0, fmt.Errorf("invalid color mode %q, expected auto, always, never, 16 or 256", mode)
+}
+
+// ColorDepth returns the number of colors the user interface uses
+func ColorDepth() int {
+	return colorDepth
+}
+
+// SetColorDepth limits all colors of the active and later themes, the syntax
+// highlighting and the file list to a number of colors. Terminals with less
+// than 8 colors are shown without colors.
+func SetColorDepth(depth int) {
+	switch {
+	case depth >= TRUE_COLORS:
+		colorDepth = TRUE_COLORS
+	case depth >= XTERM_COLORS:
+		colorDepth = XTERM_COLORS
+	case depth >= 8:
+		colorDepth = min(depth, ANSI_COLORS)
+	default:
+		colorDepth = MONOCHROME
+	}
+	nearestColorsMutex.Lock()
+	nearestColors = map[tcell.Color]tcell.Color{}
+	nearestColorsMutex.Unlock()
+	if source != nil {
+		SetCurrent(source)
+	}
+}
+
+// Quantize returns the nearest color at the color depth, or the default
+// color without colors
+func Quantize(color tcell.Color) tcell.Color {
+	if !color.Valid() || colorDepth == TRUE_COLORS {
+		return color
+	}
+	if colorDepth == MONOCHROME {
+		return tcell.ColorDefault
+	}
+	if color&tcell.ColorIsRGB == 0 && int(color-tcell.ColorValid) < colorDepth {
+		return color
+	}
+	nearestColorsMutex.Lock()
+	defer nearestColorsMutex.Unlock()
+	if nearest, isCached := nearestColors[color]; isCached {
+		return nearest
+	}
+	// The 16 standard colors are left to the terminal theme, so the 256
+	// color palette only uses the fixed colors above them
+	first := 0
+	if colorDepth == XTERM_COLORS {
+		first = len(ansiColorNames)
+	}
+	palette := make([]tcell.Color, 0, colorDepth-first)
+	for index := first; index < colorDepth; index++ {
+		palette = append(palette, tcell.PaletteColor(index))
+	}
+	nearestColors[color] = tcell.FindColor(color, palette)
+	return nearestColors[color]
+}
+
+// ColorName returns the quantized color as it is written in tview tags, "-"
+// for the default color
+func ColorName(color tcell.Color) string {
+	color = Quantize(color)
+	switch {
+	case !color.Valid():
+		return "-"
+	case color&tcell.ColorIsRGB != 0:
+		return fmt.Sprintf("#%06x", color.Hex())
+	case int(color-tcell.ColorValid) < len(ansiColorNames):
+		return ansiColorNames[color-tcell.ColorValid]
+	}
+	return "color" + strconv.Itoa(int(color-tcell.ColorValid))
+}
+
+// SelectedStyle returns the style of a selected list item on a background
+// color. Without colors the item is reversed.
+func SelectedStyle(background tcell.Color) tcell.Style {
+	if colorDepth == MONOCHROME {
+		return tcell.StyleDefault.Reverse(true)
+	}
+	return tcell.StyleDefault.Foreground(current.SelectionText).Background(background)
+}
+
+// quantize returns a copy of a theme with all colors quantized
+func (theme *Theme) quantize() *Theme {
+	quantized := *theme
+	value := reflect.ValueOf(&quantized).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		if color, isColor := value.Field(i).Interface().(tcell.Color); isColor {
+			value.Field(i).Set(reflect.ValueOf(Quantize(color)))
+		}
+	}
+	return &quantized
+}
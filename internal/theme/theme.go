@@ -1,68 +1,275 @@
 package theme
 
 import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/thilobro/gofileyourself/internal/paths"
+
 	"github.com/alecthomas/chroma"
+	"github.com/alecthomas/chroma/styles"
 	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"gopkg.in/yaml.v3"
 )
 
-type ExplorerTheme struct {
-	Bg0    tcell.Color
-	Bg1    tcell.Color
-	Fg0    tcell.Color
-	Fg1    tcell.Color
-	Gray   tcell.Color
-	Red    tcell.Color
-	Green  tcell.Color
-	Yellow tcell.Color
-	Blue   tcell.Color
-	Purple tcell.Color
-	Aqua   tcell.Color
-	Orange tcell.Color
-	Black  tcell.Color
+// DEFAULT_THEME is used when the config does not name a theme
+const DEFAULT_THEME = "gruvbox"
+
+//go:embed themes/*.yaml
+var builtinThemes embed.FS
+
+// Theme maps the roles of the user interface to colors
+type Theme struct {
+	Name             string
+	Background       tcell.Color
+	Panel            tcell.Color
+	Text             tcell.Color
+	TextStrong       tcell.Color
+	Muted            tcell.Color
+	Header           tcell.Color
+	SelectionText    tcell.Color
+	Selection        tcell.Color
+	SelectionParent  tcell.Color
+	SelectionPreview tcell.Color
+	Mark             tcell.Color
+	SearchHighlight  tcell.Color
+	Error            tcell.Color
+	Success          tcell.Color
+	Accent           tcell.Color
+	Danger           tcell.Color
+	Directory        tcell.Color
+	Symlink          tcell.Color
+	BrokenLink       tcell.Color
+	Executable       tcell.Color
+	Special          tcell.Color
+	SyntaxStyle      *chroma.Style
+}
+
+// definition is the YAML form of a theme. Roles and token colors refer to
+// palette names or are colors themselves.
+type definition struct {
+	Palette map[string]string `yaml:"palette"`
+	Roles   map[string]string `yaml:"roles"`
+	Syntax  *syntaxDefinition `yaml:"syntax"`
+}
+
+type syntaxDefinition struct {
+	// Style names a chroma style that the tokens are added to
+	Style  string            `yaml:"style"`
+	Tokens map[string]string `yaml:"tokens"`
+}
+
+var current *Theme
+
+func init() {
+	SetCurrent(mustLoadBuiltin(DEFAULT_THEME))
+}
+
+// Current returns the active theme
+func Current() *Theme {
+	return current
+}
+
+// SetCurrent makes a theme the active one, with its colors quantized to the
+// color depth. It also sets the default colors of tview for primitives that
+// are not styled explicitly.
+func SetCurrent(theme *Theme) {
+	source = theme
+	current = theme.quantize()
+	theme = current
+	tview.Styles.PrimitiveBackgroundColor = theme.Background
+	tview.Styles.ContrastBackgroundColor = theme.Panel
+	tview.Styles.MoreContrastBackgroundColor = theme.Panel
+	tview.Styles.BorderColor = theme.Muted
+	tview.Styles.TitleColor = theme.Header
+	tview.Styles.GraphicsColor = theme.Muted
+	tview.Styles.PrimaryTextColor = theme.Text
+	tview.Styles.SecondaryTextColor = theme.TextStrong
+	tview.Styles.TertiaryTextColor = theme.Accent
+	tview.Styles.InverseTextColor = theme.SelectionText
+	tview.Styles.ContrastSecondaryTextColor = theme.TextStrong
+}
+
+// Tag returns a tview color tag for a color and attributes like "b", e.g.
+// [#fb4934::b]. The color is quantized to the color depth.
+func Tag(color tcell.Color, attributes string) string {
+	return fmt.Sprintf("[%s::%s]", ColorName(color), attributes)
+}
+
+// Dir returns the directory of user themes
+func Dir() string {
+	return filepath.Join(paths.ConfigHome(), paths.APP_NAME, "themes")
+}
+
+// Names returns the names of the built-in and user themes
+func Names() []string {
+	names := []string{}
+	entries, _ := builtinThemes.ReadDir("themes")
+	userEntries, _ := os.ReadDir(Dir())
+	for _, entry := range append(entries, userEntries...) {
+		if name, isTheme := strings.CutSuffix(entry.Name(), ".yaml"); isTheme && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Load reads a theme by name from the user themes, then the built-in ones, or
+// from a file if name is a path. Palette and roles that a theme leaves out are
+// taken from the default theme.
+func Load(name string) (*Theme, error) {
+	var content []byte
+	var err error
+	if strings.ContainsRune(name, '/') {
+		content, err = os.ReadFile(paths.Expand(name))
+	} else {
+		content, err = os.ReadFile(filepath.Join(Dir(), name+".yaml"))
+		if errors.Is(err, fs.ErrNotExist) {
+			content, err = builtinThemes.ReadFile("themes/" + name + ".yaml")
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil, fmt.Errorf("unknown theme: %s", name)
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	base, err := builtinThemes.ReadFile("themes/" + DEFAULT_THEME + ".yaml")
+	if err != nil {
+		return nil, err
+	}
+	var baseDefinition definition
+	if err := yaml.Unmarshal(base, &baseDefinition); err != nil {
+		return nil, err
+	}
+	// Palette and roles are merged into the ones of the default theme, the
+	// syntax section is replaced as a whole
+	themeDefinition := definition{Palette: baseDefinition.Palette, Roles: baseDefinition.Roles}
+	if err := yaml.Unmarshal(content, &themeDefinition); err != nil {
+		return nil, fmt.Errorf("theme %s: %w", name, err)
+	}
+	if themeDefinition.Syntax == nil {
+		themeDefinition.Syntax = baseDefinition.Syntax
+	}
+	theme, err := themeDefinition.build(name)
+	if err != nil {
+		return nil, fmt.Errorf("theme %s: %w", name, err)
+	}
+	return theme, nil
 }
 
-func GetExplorerTheme() *ExplorerTheme {
-	return &ExplorerTheme{
-		Bg0:    tcell.NewRGBColor(40, 40, 40),    // #282828
-		Bg1:    tcell.NewRGBColor(60, 56, 54),    // #3c3836
-		Fg0:    tcell.NewRGBColor(251, 241, 199), // #fbf1c7
-		Fg1:    tcell.NewRGBColor(235, 219, 178), // #ebdbb2
-		Gray:   tcell.NewRGBColor(146, 131, 116), // #928374
-		Red:    tcell.NewRGBColor(251, 73, 52),   // #fb4934
-		Green:  tcell.NewRGBColor(184, 187, 38),  // #b8bb26
-		Yellow: tcell.NewRGBColor(250, 189, 47),  // #fabd2f
-		Blue:   tcell.NewRGBColor(131, 165, 152), // #83a598
-		Purple: tcell.NewRGBColor(211, 134, 155), // #d3869b
-		Aqua:   tcell.NewRGBColor(142, 192, 124), // #8ec07c
-		Orange: tcell.NewRGBColor(254, 128, 25),  // #fe8019
-		Black:  tcell.NewRGBColor(0, 0, 0),       // #000000
+func mustLoadBuiltin(name string) *Theme {
+	theme, err := Load(name)
+	if err != nil {
+		panic(err)
 	}
+	return theme
 }
 
-func GetFormatterStyle() *chroma.Style {
-	return chroma.MustNewStyle("gruvbox", chroma.StyleEntries{
-		chroma.Text:               "#ebdbb2",
-		chroma.Error:              "#fb4934",
-		chroma.Comment:            "#928374",
-		chroma.Keyword:            "#fb4934",
-		chroma.KeywordConstant:    "#d3869b",
-		chroma.KeywordDeclaration: "#fb4934",
-		chroma.KeywordNamespace:   "#fb4934",
-		chroma.KeywordType:        "#fabd2f",
-		chroma.Operator:           "#ebdbb2",
-		chroma.Punctuation:        "#ebdbb2",
-		chroma.Name:               "#ebdbb2",
-		chroma.NameAttribute:      "#b8bb26",
-		chroma.NameBuiltin:        "#fabd2f",
-		chroma.NameClass:          "#8ec07c",
-		chroma.NameConstant:       "#d3869b",
-		chroma.NameDecorator:      "#d3869b",
-		chroma.NameFunction:       "#b8bb26",
-		chroma.NameTag:            "#fb4934",
-		chroma.NameVariable:       "#ebdbb2",
-		chroma.Literal:            "#d3869b",
-		chroma.LiteralNumber:      "#d3869b",
-		chroma.LiteralString:      "#b8bb26",
-		chroma.Background:         "#282828",
-	})
+// color resolves a palette name or a color name like #fe8019
+func (themeDefinition *definition) color(value string) (tcell.Color, error) {
+	if paletteValue, isPaletteName := themeDefinition.Palette[value]; isPaletteName {
+		value = paletteValue
+	}
+	color := tcell.GetColor(value)
+	if color == tcell.ColorDefault {
+		return color, fmt.Errorf("invalid color %q", value)
+	}
+	return color, nil
+}
+
+func (themeDefinition *definition) build(name string) (*Theme, error) {
+	theme := &Theme{Name: name}
+	roles := map[string]*tcell.Color{
+		"background":        &theme.Background,
+		"panel":             &theme.Panel,
+		"text":              &theme.Text,
+		"text_strong":       &theme.TextStrong,
+		"muted":             &theme.Muted,
+		"header":            &theme.Header,
+		"selection_text":    &theme.SelectionText,
+		"selection":         &theme.Selection,
+		"selection_parent":  &theme.SelectionParent,
+		"selection_preview": &theme.SelectionPreview,
+		"mark":              &theme.Mark,
+		"search_highlight":  &theme.SearchHighlight,
+		"error":             &theme.Error,
+		"success":           &theme.Success,
+		"accent":            &theme.Accent,
+		"danger":            &theme.Danger,
+		"directory":         &theme.Directory,
+		"symlink":           &theme.Symlink,
+		"broken_link":       &theme.BrokenLink,
+		"executable":        &theme.Executable,
+		"special":           &theme.Special,
+	}
+	for role, value := range themeDefinition.Roles {
+		target, isRole := roles[role]
+		if !isRole {
+			return nil, fmt.Errorf("unknown role %q", role)
+		}
+		color, err := themeDefinition.color(value)
+		if err != nil {
+			return nil, fmt.Errorf("role %s: %w", role, err)
+		}
+		*target = color
+	}
+
+	syntaxStyle, err := themeDefinition.syntaxStyle(name)
+	if err != nil {
+		return nil, err
+	}
+	theme.SyntaxStyle = syntaxStyle
+	return theme, nil
+}
+
+// syntaxStyle builds the chroma style from a named style and token colors.
+// Token colors are chroma style entries like "bold #fb4934 bg:bg0" in which
+// palette names may be used.
+func (themeDefinition *definition) syntaxStyle(name string) (*chroma.Style, error) {
+	builder := chroma.NewStyleBuilder(name)
+	if themeDefinition.Syntax == nil {
+		return builder.Build()
+	}
+	if themeDefinition.Syntax.Style != "" {
+		style, isRegistered := styles.Registry[themeDefinition.Syntax.Style]
+		if !isRegistered {
+			return nil, fmt.Errorf("unknown syntax style %q", themeDefinition.Syntax.Style)
+		}
+		builder = style.Builder()
+	}
+	tokenTypes := map[string]chroma.TokenType{}
+	for tokenType := range chroma.StandardTypes {
+		tokenTypes[tokenType.String()] = tokenType
+	}
+	for tokenName, entry := range themeDefinition.Syntax.Tokens {
+		tokenType, isTokenType := tokenTypes[tokenName]
+		if !isTokenType {
+			return nil, fmt.Errorf("unknown token type %q", tokenName)
+		}
+		words := strings.Fields(entry)
+		for i, word := range words {
+			prefix, value, hasPrefix := strings.Cut(word, ":")
+			if !hasPrefix {
+				prefix, value = "", word
+			} else {
+				prefix += ":"
+			}
+			if paletteValue, isPaletteName := themeDefinition.Palette[value]; isPaletteName {
+				words[i] = prefix + paletteValue
+			}
+		}
+		builder.Add(tokenType, strings.Join(words, " "))
+	}
+	return builder.Build()
 }
@@ -0,0 +1,260 @@
+package trash
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/thilobro/gofileyourself/internal/config"
+	"github.com/thilobro/gofileyourself/internal/helper"
+	"github.com/thilobro/gofileyourself/internal/theme"
+	"github.com/thilobro/gofileyourself/internal/widget"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// Browser lists the trashed items and allows restoring or purging them
+type Browser struct {
+	context      *widget.Context
+	rootFlex     *tview.Flex
+	header       *tview.TextView
+	itemList     *tview.List
+	selectedList tview.Primitive
+	footer       *tview.TextView
+	items        []Item
+	keyBuffer    string
+	// pendingAction runs once the question in the footer is answered with y
+	pendingAction func()
+}
+
+func NewBrowser(context *widget.Context) (*Browser, error) {
+	browser := &Browser{
+		context:  context,
+		rootFlex: tview.NewFlex(),
+		header:   tview.NewTextView(),
+		itemList: tview.NewList().ShowSecondaryText(false),
+		footer:   tview.NewTextView().SetDynamicColors(true),
+	}
+	// An unreadable trash is shown as empty, with the error in the footer
+	if err := browser.loadItems(); err != nil {
+		browser.setStatus(err.Error())
+	}
+	browser.SetupKeyBindings()
+	browser.setCurrentLine(0)
+	browser.Draw()
+	return browser, nil
+}
+
+func (browser *Browser) loadItems() error {
+	items, err := List()
+	if err != nil {
+		return err
+	}
+	browser.items = items
+	currentItem := browser.itemList.GetCurrentItem()
+	browser.itemList.Clear()
+	for _, item := range items {
+		line := item.DeletionDate.Format("2006-01-02 15:04") + "  " + tview.Escape(item.OriginalPath)
+		browser.itemList.AddItem(line, item.OriginalPath, 0, nil)
+	}
+	browser.setCurrentLine(currentItem)
+	return nil
+}
+
+func (browser *Browser) setCurrentLine(lineIndex int) {
+	if lineIndex >= browser.itemList.GetItemCount() {
+		lineIndex = browser.itemList.GetItemCount() - 1
+	}
+	if lineIndex < 0 {
+		lineIndex = 0
+	}
+	browser.itemList.SetCurrentItem(lineIndex)
+	item, ok := browser.currentItem()
+	if !ok {
+		textView := tview.NewTextView().SetDynamicColors(true)
+		textView.SetText(theme.Tag(theme.Current().Muted, "") + "Trash is empty...[-::]")
+		browser.selectedList = textView
+		return
+	}
+	preview, err := helper.LoadDirectory(item.FilePath(), helper.ListOptions{ShowHidden: true, Sort: config.SortName, DirsFirst: true}, false, []string{})
+	if err != nil || preview == nil {
+		textView, err := helper.LoadFilePreview(item.FilePath(), browser.context.Config.Preview)
+		if err != nil {
+			errorView := tview.NewTextView().SetDynamicColors(true)
+			errorView.SetText(theme.Tag(theme.Current().Error, "") + tview.Escape(err.Error()) + "[-::]")
+			browser.selectedList = errorView
+			return
+		}
+		browser.selectedList = textView
+		return
+	}
+	browser.selectedList = preview
+}
+
+func (browser *Browser) currentItem() (Item, bool) {
+	index := browser.itemList.GetCurrentItem()
+	if index < 0 || index >= len(browser.items) {
+		return Item{}, false
+	}
+	return browser.items[index], true
+}
+
+func (browser *Browser) setStatus(text string) {
+	browser.footer.SetText(tview.Escape(text))
+}
+
+// confirm asks a question in the footer and runs action once it is answered
+// with y or Enter. Any other key cancels it.
+func (browser *Browser) confirm(question string, action func()) {
+	browser.pendingAction = action
+	browser.footer.SetText(theme.Tag(theme.Current().Danger, "b") + tview.Escape(question) + " [y/N][-::-]")
+}
+
+// answerConfirmation runs or drops the pending action
+func (browser *Browser) answerConfirmation(event *tcell.EventKey) {
+	action := browser.pendingAction
+	browser.pendingAction = nil
+	if event.Key() == tcell.KeyEnter || event.Rune() == 'y' || event.Rune() == 'Y' {
+		action()
+	} else {
+		browser.setStatus("Cancelled")
+	}
+}
+
+func (browser *Browser) restoreCurrentItem() {
+	item, ok := browser.currentItem()
+	if !ok {
+		return
+	}
+	if err := Restore(item); err != nil {
+		browser.setStatus(err.Error())
+		return
+	}
+	browser.setStatus("Restored " + item.OriginalPath)
+	browser.loadItems()
+}
+
+func (browser *Browser) removeCurrentItem() {
+	item, ok := browser.currentItem()
+	if !ok {
+		return
+	}
+	browser.confirm("Permanently delete "+item.OriginalPath+"?", func() {
+		if err := Remove(item); err != nil {
+			browser.setStatus(err.Error())
+			return
+		}
+		browser.setStatus("Deleted " + item.OriginalPath)
+		browser.loadItems()
+	})
+}
+
+func (browser *Browser) emptyTrash() {
+	if len(browser.items) == 0 {
+		return
+	}
+	browser.confirm(fmt.Sprintf("Permanently delete all %d items?", len(browser.items)), func() {
+		count := len(browser.items)
+		if err := Empty(); err != nil {
+			browser.setStatus(err.Error())
+			return
+		}
+		browser.setStatus(fmt.Sprintf("Deleted %d items", count))
+		browser.loadItems()
+	})
+}
+
+func (browser *Browser) SetupKeyBindings() {
+	browser.rootFlex.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		defer browser.Draw()
+		if browser.pendingAction != nil {
+			browser.answerConfirmation(event)
+			return nil
+		}
+		rune := event.Rune()
+		browser.keyBuffer += string(rune)
+		if len(browser.keyBuffer) > 2 {
+			browser.keyBuffer = browser.keyBuffer[1:]
+		}
+		if strings.HasSuffix(browser.keyBuffer, "dd") {
+			browser.keyBuffer = ""
+			browser.removeCurrentItem()
+			return nil
+		} else if strings.HasSuffix(browser.keyBuffer, "gg") {
+			browser.keyBuffer = ""
+			browser.setCurrentLine(0)
+			return nil
+		}
+		switch rune {
+		case 'j':
+			browser.setCurrentLine(browser.itemList.GetCurrentItem() + 1)
+		case 'k':
+			browser.setCurrentLine(browser.itemList.GetCurrentItem() - 1)
+		case 'G':
+			browser.setCurrentLine(browser.itemList.GetItemCount() - 1)
+		case 'r':
+			browser.restoreCurrentItem()
+		case 'E':
+			browser.emptyTrash()
+		case 'q', 'h':
+			browser.context.OnWidgetResult(widget.Trash, "")
+		}
+		return nil
+	})
+}
+
+func (browser *Browser) Root() tview.Primitive {
+	return browser.rootFlex
+}
+
+func (browser *Browser) Draw() {
+	browser.header.SetBorder(true).SetTitle("Trash").Blur()
+	browser.header.SetText(fmt.Sprintf("%s (%d items)", HomeTrashDir(), len(browser.items)))
+	listFlex := tview.NewFlex()
+	listFlex.AddItem(browser.itemList, 0, 1, true)
+	listFlex.AddItem(tview.NewBox(), 2, 0, false)
+	if browser.selectedList != nil {
+		listFlex.AddItem(browser.selectedList, 0, 1, false)
+	}
+	browser.rootFlex.Clear()
+	browser.rootFlex.SetDirection(tview.FlexRow)
+	browser.rootFlex.AddItem(browser.header, 3, 0, false)
+	browser.rootFlex.AddItem(listFlex, 0, 1, true)
+	browser.rootFlex.AddItem(browser.footer, 1, 0, false)
+	browser.context.App.SetFocus(browser.itemList)
+	browser.applyTheme(listFlex)
+}
+
+func (browser *Browser) Run() error {
+	return browser.context.App.SetRoot(browser.Root(), true).Run()
+}
+
+func (browser *Browser) applyTheme(listFlex *tview.Flex) {
+	explorerTheme := theme.Current()
+
+	browser.rootFlex.SetBackgroundColor(explorerTheme.Background)
+	listFlex.SetBackgroundColor(explorerTheme.Background)
+	browser.header.SetBackgroundColor(explorerTheme.Background)
+	browser.footer.
+		SetTextColor(explorerTheme.TextStrong).
+		SetBackgroundColor(explorerTheme.Background)
+	browser.itemList.
+		SetMainTextColor(explorerTheme.Text).
+		SetSelectedStyle(theme.SelectedStyle(explorerTheme.Danger)).
+		SetBackgroundColor(explorerTheme.Background)
+	if list, ok := browser.selectedList.(*tview.List); ok {
+		list.
+			SetMainTextColor(explorerTheme.Text).
+			SetSelectedStyle(theme.SelectedStyle(explorerTheme.SelectionPreview)).
+			SetBackgroundColor(explorerTheme.Background)
+	} else if textView, ok := browser.selectedList.(*tview.TextView); ok {
+		textView.
+			SetTextColor(explorerTheme.TextStrong).
+			SetBackgroundColor(explorerTheme.Background)
+	}
+}
+
+// GetInputCapture returns the input capture function for the trash browser
+func (browser *Browser) GetInputCapture() func(*tcell.EventKey) *tcell.EventKey {
+	return browser.rootFlex.GetInputCapture()
+}
@@ -0,0 +1,336 @@
+package trash
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/thilobro/gofileyourself/internal/paths"
+
+	"github.com/otiai10/copy"
+)
+
+const (
+	infoSuffix = ".trashinfo"
+	dateLayout = "2006-01-02T15:04:05"
+)
+
+// Item is a single entry of a trash directory as described by the
+// freedesktop.org Trash specification.
+type Item struct {
+	Name         string
+	TrashDir     string
+	OriginalPath string
+	DeletionDate time.Time
+}
+
+// FilePath returns the location of the trashed file inside the trash directory
+func (item Item) FilePath() string {
+	return filepath.Join(item.TrashDir, "files", item.Name)
+}
+
+// InfoPath returns the location of the .trashinfo file of the item
+func (item Item) InfoPath() string {
+	return filepath.Join(item.TrashDir, "info", item.Name+infoSuffix)
+}
+
+// HomeTrashDir returns $XDG_DATA_HOME/Trash
+func HomeTrashDir() string {
+	return filepath.Join(paths.DataHome(), "Trash")
+}
+
+// Trash moves the given file or directory into the matching trash directory
+func Trash(path string) error {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Lstat(absPath); err != nil {
+		return err
+	}
+
+	homeTrash := HomeTrashDir()
+	if err := ensureTrashDir(homeTrash); err != nil {
+		return err
+	}
+	trashDir := homeTrash
+	topDir := ""
+	if !sameDevice(absPath, homeTrash) {
+		topDir = mountTopDir(absPath)
+		if mountTrash, err := mountTrashDir(topDir, true); err == nil {
+			trashDir = mountTrash
+		} else {
+			topDir = ""
+		}
+	}
+
+	originalPath := absPath
+	if topDir != "" {
+		originalPath, _ = filepath.Rel(topDir, absPath)
+	}
+
+	name, infoFile, err := reserveName(trashDir, filepath.Base(absPath))
+	if err != nil {
+		return err
+	}
+	info := "[Trash Info]\n" +
+		"Path=" + escapePath(originalPath) + "\n" +
+		"DeletionDate=" + time.Now().Format(dateLayout) + "\n"
+	_, err = infoFile.WriteString(info)
+	infoFile.Close()
+	item := Item{Name: name, TrashDir: trashDir}
+	if err != nil {
+		os.Remove(item.InfoPath())
+		return err
+	}
+
+	if err := moveFile(absPath, item.FilePath()); err != nil {
+		os.Remove(item.InfoPath())
+		return err
+	}
+	return nil
+}
+
+// List returns the items of the home trash and of all trash directories
+// found on mounted file systems, newest first
+func List() ([]Item, error) {
+	items, err := listTrashDir(HomeTrashDir(), "")
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, topDir := range mountPoints() {
+		trashDir, err := mountTrashDir(topDir, false)
+		if err != nil || trashDir == HomeTrashDir() {
+			continue
+		}
+		mountItems, err := listTrashDir(trashDir, topDir)
+		if err != nil {
+			continue
+		}
+		items = append(items, mountItems...)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].DeletionDate.After(items[j].DeletionDate)
+	})
+	return items, nil
+}
+
+// Restore moves a trashed item back to its original location
+func Restore(item Item) error {
+	if _, err := os.Lstat(item.OriginalPath); err == nil {
+		return fmt.Errorf("%s already exists", item.OriginalPath)
+	}
+	if err := os.MkdirAll(filepath.Dir(item.OriginalPath), 0o755); err != nil {
+		return err
+	}
+	if err := moveFile(item.FilePath(), item.OriginalPath); err != nil {
+		return err
+	}
+	return os.Remove(item.InfoPath())
+}
+
+// Remove permanently deletes a trashed item
+func Remove(item Item) error {
+	if err := os.RemoveAll(item.FilePath()); err != nil {
+		return err
+	}
+	return os.Remove(item.InfoPath())
+}
+
+// Empty permanently deletes all trashed items
+func Empty() error {
+	items, err := List()
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		if err := Remove(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func ensureTrashDir(trashDir string) error {
+	for _, dir := range []string{"files", "info"} {
+		if err := os.MkdirAll(filepath.Join(trashDir, dir), 0o700); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reserveName atomically creates the .trashinfo file for a free name
+func reserveName(trashDir string, baseName string) (string, *os.File, error) {
+	name := baseName
+	for i := 2; ; i++ {
+		infoPath := filepath.Join(trashDir, "info", name+infoSuffix)
+		file, err := os.OpenFile(infoPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if err == nil {
+			if _, err := os.Lstat(filepath.Join(trashDir, "files", name)); err == nil {
+				file.Close()
+				os.Remove(infoPath)
+			} else {
+				return name, file, nil
+			}
+		} else if !os.IsExist(err) {
+			return "", nil, err
+		}
+		name = baseName + "." + strconv.Itoa(i)
+	}
+}
+
+func listTrashDir(trashDir string, topDir string) ([]Item, error) {
+	entries, err := os.ReadDir(filepath.Join(trashDir, "info"))
+	if err != nil {
+		return nil, err
+	}
+	items := []Item{}
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), infoSuffix) {
+			continue
+		}
+		item := Item{
+			Name:     strings.TrimSuffix(entry.Name(), infoSuffix),
+			TrashDir: trashDir,
+		}
+		if err := readInfo(&item, topDir); err != nil {
+			continue
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func readInfo(item *Item, topDir string) error {
+	file, err := os.Open(item.InfoPath())
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		key, value, found := strings.Cut(scanner.Text(), "=")
+		if !found {
+			continue
+		}
+		switch key {
+		case "Path":
+			path, err := url.PathUnescape(value)
+			if err != nil {
+				return err
+			}
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(topDir, path)
+			}
+			item.OriginalPath = path
+		case "DeletionDate":
+			item.DeletionDate, _ = time.ParseInLocation(dateLayout, value, time.Local)
+		}
+	}
+	if item.OriginalPath == "" {
+		return errors.New("trashinfo without path")
+	}
+	return scanner.Err()
+}
+
+// mountTrashDir returns $topdir/.Trash/$uid if the administrator created a
+// valid shared trash, otherwise $topdir/.Trash-$uid
+func mountTrashDir(topDir string, create bool) (string, error) {
+	uid := strconv.Itoa(os.Getuid())
+	sharedTrash := filepath.Join(topDir, ".Trash")
+	if info, err := os.Lstat(sharedTrash); err == nil &&
+		info.IsDir() && info.Mode()&os.ModeSticky != 0 {
+		trashDir := filepath.Join(sharedTrash, uid)
+		if create {
+			if err := ensureTrashDir(trashDir); err == nil {
+				return trashDir, nil
+			}
+		} else if _, err := os.Stat(trashDir); err == nil {
+			return trashDir, nil
+		}
+	}
+	trashDir := filepath.Join(topDir, ".Trash-"+uid)
+	if create {
+		return trashDir, ensureTrashDir(trashDir)
+	}
+	_, err := os.Stat(trashDir)
+	return trashDir, err
+}
+
+// mountTopDir walks up from path until the device changes
+func mountTopDir(path string) string {
+	dir := filepath.Dir(path)
+	for dir != "/" {
+		parent := filepath.Dir(dir)
+		if !sameDevice(dir, parent) {
+			return dir
+		}
+		dir = parent
+	}
+	return dir
+}
+
+func mountPoints() []string {
+	content, err := os.ReadFile("/proc/mounts")
+	if err != nil {
+		return []string{}
+	}
+	mounts := []string{}
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		// /proc/mounts escapes spaces and tabs as octal sequences
+		mountPoint, err := strconv.Unquote("\"" + fields[1] + "\"")
+		if err != nil {
+			mountPoint = fields[1]
+		}
+		mounts = append(mounts, mountPoint)
+	}
+	return mounts
+}
+
+func sameDevice(a string, b string) bool {
+	aInfo, err := os.Lstat(a)
+	if err != nil {
+		return false
+	}
+	bInfo, err := os.Lstat(b)
+	if err != nil {
+		return false
+	}
+	aStat, aOk := aInfo.Sys().(*syscall.Stat_t)
+	bStat, bOk := bInfo.Sys().(*syscall.Stat_t)
+	return aOk && bOk && aStat.Dev == bStat.Dev
+}
+
+// moveFile renames src to dst and falls back to copying across devices
+func moveFile(src string, dst string) error {
+	err := os.Rename(src, dst)
+	if err == nil {
+		return nil
+	}
+	var linkErr *os.LinkError
+	if !errors.As(err, &linkErr) || !errors.Is(linkErr.Err, syscall.EXDEV) {
+		return err
+	}
+	if err := copy.Copy(src, dst); err != nil {
+		return err
+	}
+	return os.RemoveAll(src)
+}
+
+func escapePath(path string) string {
+	return (&url.URL{Path: path}).EscapedPath()
+}
@@ -12,16 +12,19 @@ const (
 	Explorer Mode = iota
 	Find
 	FindRecent
+	Trash
 )
 
+// Context is shared by all widgets. Config is the effective config for
+// CurrentPath, GlobalConfig the one without the local config files.
 type Context struct {
 	App              *tview.Application
 	CurrentPath      string
-	ShowHiddenFiles  bool
 	OnWidgetResult   func(mode Mode, result string)
 	ChooseFilePath   *string
 	SelectedFilePath *string
 	Config           *config.Config
+	GlobalConfig     *config.Config
 }
 
 type WidgetInterface interface {
@@ -32,6 +35,13 @@ type WidgetInterface interface {
 	GetInputCapture() func(*tcell.EventKey) *tcell.EventKey
 }
 
+// ConfigReloader is implemented by widgets that react to a changed config
+// file. err is set when the file could not be loaded and the old config stays
+// in place.
+type ConfigReloader interface {
+	ReloadConfig(err error)
+}
+
 type Factory interface {
 	New(ctx *Context) (WidgetInterface, error)
 }